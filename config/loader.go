@@ -15,12 +15,26 @@ type YAMLConfig struct {
 		ArrivalRate    float64 `yaml:"arrival_rate"`
 		ServiceRate    float64 `yaml:"service_rate"`
 		MaxQueueSize   int     `yaml:"max_queue_size"`
+		Servers        int     `yaml:"servers"`
 		MaxCustomers   int     `yaml:"max_customers"`
+		Replications   int     `yaml:"replications"`
+		Parallelism    int     `yaml:"parallelism"`
 		StopCondition  struct {
 			AutomaticMode bool    `yaml:"automatic_mode"`
 			Type          string  `yaml:"type"`
 			Value         float64 `yaml:"value"`
 			TimeLimit     float64 `yaml:"time_limit"`
+			Conditions    []struct {
+				Type  string  `yaml:"type"`
+				Value float64 `yaml:"value"`
+			} `yaml:"conditions"`
+			Precision struct {
+				Metrics         []string `yaml:"metrics"`
+				Relative        float64  `yaml:"relative"`
+				Absolute        float64  `yaml:"absolute"`
+				MaxReplications int      `yaml:"max_replications"`
+				CheckInterval   float64  `yaml:"check_interval"`
+			} `yaml:"precision"`
 		} `yaml:"stop_condition"`
 		Visualization struct {
 			Enabled             bool `yaml:"enabled"`
@@ -29,9 +43,133 @@ type YAMLConfig struct {
 			ProgressBarWidth    int  `yaml:"progress_bar_width"`
 		} `yaml:"visualization"`
 		Random struct {
-			Seed         int64  `yaml:"seed"`
-			Distribution string `yaml:"distribution"`
+			Seed                int64  `yaml:"seed"`
+			Distribution        string `yaml:"distribution"`
+			ArrivalDistribution string `yaml:"arrival_distribution"`
+			ServiceDistribution string `yaml:"service_distribution"`
+			ArrivalTrace        string `yaml:"arrival_trace"`
 		} `yaml:"random"`
+		VarianceReduction struct {
+			CommonRandomNumbers bool   `yaml:"common_random_numbers"`
+			Antithetic          bool   `yaml:"antithetic"`
+			Pairs               int    `yaml:"pairs"`
+			Metric              string `yaml:"metric"`
+		} `yaml:"variance_reduction"`
+		ControlVariates struct {
+			Enabled bool `yaml:"enabled"`
+			Batches int  `yaml:"batches"`
+		} `yaml:"control_variates"`
+		Warmup struct {
+			Time              float64 `yaml:"time"`
+			Customers         int     `yaml:"customers"`
+			Method            string  `yaml:"method"`
+			PilotReplications int     `yaml:"pilot_replications"`
+			WelchWindow       int     `yaml:"welch_window"`
+		} `yaml:"warmup"`
+		OutputAnalysis struct {
+			Method          string  `yaml:"method"`
+			Batches         int     `yaml:"batches"`
+			ConfidenceLevel float64 `yaml:"confidence_level"`
+		} `yaml:"output_analysis"`
+		Regenerative struct {
+			Enabled bool `yaml:"enabled"`
+		} `yaml:"regenerative"`
+		Quantiles struct {
+			Levels           []float64 `yaml:"levels"`
+			CIMethod         string    `yaml:"ci_method"`
+			BootstrapSamples int       `yaml:"bootstrap_samples"`
+		} `yaml:"quantiles"`
+		Streaming struct {
+			Enabled          bool    `yaml:"enabled"`
+			RelativeAccuracy float64 `yaml:"relative_accuracy"`
+		} `yaml:"streaming"`
+		Analytic struct {
+			Enabled bool `yaml:"enabled"`
+		} `yaml:"analytic"`
+		Consistency struct {
+			Tolerance float64 `yaml:"tolerance"`
+		} `yaml:"consistency"`
+		Departures struct {
+			Lags       int    `yaml:"lags"`
+			ExportFile string `yaml:"export_file"`
+		} `yaml:"departures"`
+		SLA struct {
+			Targets []struct {
+				Name      string  `yaml:"name"`
+				Metric    string  `yaml:"metric"`
+				Type      string  `yaml:"type"`
+				Threshold float64 `yaml:"threshold"`
+				Target    float64 `yaml:"target"`
+			} `yaml:"targets"`
+		} `yaml:"sla"`
+		Cost struct {
+			WaitingCost      float64 `yaml:"waiting_cost"`
+			HoldingCost      float64 `yaml:"holding_cost"`
+			ServerCost       float64 `yaml:"server_cost"`
+			ServiceCost      float64 `yaml:"service_cost"`
+			RejectionPenalty float64 `yaml:"rejection_penalty"`
+			Revenue          float64 `yaml:"revenue"`
+		} `yaml:"cost"`
+		TimeSeries struct {
+			Window     float64 `yaml:"window"`
+			Step       float64 `yaml:"step"`
+			ExportFile string  `yaml:"export_file"`
+		} `yaml:"time_series"`
+		Transient struct {
+			Replications     int       `yaml:"replications"`
+			InitialCustomers int       `yaml:"initial_customers"`
+			Times            []float64 `yaml:"times"`
+			Step             float64   `yaml:"step"`
+			ExportFile       string    `yaml:"export_file"`
+		} `yaml:"transient"`
+		Sweep struct {
+			Parameters []struct {
+				Field  string   `yaml:"field"`
+				Values []string `yaml:"values"`
+				Range  string   `yaml:"range"`
+			} `yaml:"parameters"`
+			Metrics    []string `yaml:"metrics"`
+			OutputFile string   `yaml:"output_file"`
+		} `yaml:"sweep"`
+		Design struct {
+			Type       string `yaml:"type"`
+			Resolution int    `yaml:"resolution"`
+			Samples    int    `yaml:"samples"`
+			Factors    []struct {
+				Field   string  `yaml:"field"`
+				Low     float64 `yaml:"low"`
+				High    float64 `yaml:"high"`
+				Integer bool    `yaml:"integer"`
+			} `yaml:"factors"`
+			Responses  []string `yaml:"responses"`
+			OutputFile string   `yaml:"output_file"`
+		} `yaml:"design"`
+		Optimize struct {
+			Field       string   `yaml:"field"`
+			Values      []string `yaml:"values"`
+			Range       string   `yaml:"range"`
+			CostMetric  string   `yaml:"cost_metric"`
+			Constraints []struct {
+				Metric string  `yaml:"metric"`
+				Type   string  `yaml:"type"`
+				Limit  float64 `yaml:"limit"`
+			} `yaml:"constraints"`
+			MaxReplications int `yaml:"max_replications"`
+		} `yaml:"optimize"`
+		Selection struct {
+			Metric              string  `yaml:"metric"`
+			Goal                string  `yaml:"goal"`
+			Indifference        float64 `yaml:"indifference"`
+			Probability         float64 `yaml:"probability"`
+			InitialReplications int     `yaml:"initial_replications"`
+			MaxReplications     int     `yaml:"max_replications"`
+			CommonRandomNumbers bool    `yaml:"common_random_numbers"`
+		} `yaml:"selection"`
+		Metamodel struct {
+			Response   string `yaml:"response"`
+			Degree     int    `yaml:"degree"`
+			OutputFile string `yaml:"output_file"`
+		} `yaml:"metamodel"`
 		Logging struct {
 			Level        string `yaml:"level"`
 			LogToFile    bool   `yaml:"log_to_file"`
@@ -52,8 +190,23 @@ func LoadConfig(filename string) (*models.SimulationConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %v", err)
 	}
+	return finishConfig(&yamlConfig)
+}
 
-	return convertToModel(&yamlConfig), nil
+// finishConfig converts a parsed file and loads the files it refers to.
+func finishConfig(yamlConfig *YAMLConfig) (*models.SimulationConfig, error) {
+	var err error
+	cfg := convertToModel(yamlConfig)
+	if cfg.Random.ArrivalDistribution == "trace" && cfg.Random.ArrivalTrace == "" {
+		return nil, fmt.Errorf("arrival_distribution trace needs random.arrival_trace")
+	}
+	if cfg.Random.ArrivalTrace != "" {
+		cfg.Random.ArrivalTimes, err = loadArrivalTrace(cfg.Random.ArrivalTrace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
 }
 
 func convertToModel(yamlConfig *YAMLConfig) *models.SimulationConfig {
@@ -62,12 +215,23 @@ func convertToModel(yamlConfig *YAMLConfig) *models.SimulationConfig {
 		ArrivalRate:    yamlConfig.Simulation.ArrivalRate,
 		ServiceRate:    yamlConfig.Simulation.ServiceRate,
 		MaxQueueSize:   yamlConfig.Simulation.MaxQueueSize,
+		Servers:        yamlConfig.Simulation.Servers,
 		MaxCustomers:   yamlConfig.Simulation.MaxCustomers,
+		Replications:   yamlConfig.Simulation.Replications,
+		Parallelism:    yamlConfig.Simulation.Parallelism,
 		StopCondition: models.StopCondition{
 			AutomaticMode: yamlConfig.Simulation.StopCondition.AutomaticMode,
 			Type:          yamlConfig.Simulation.StopCondition.Type,
-			Value:         int(yamlConfig.Simulation.StopCondition.Value),
+			Value:         yamlConfig.Simulation.StopCondition.Value,
 			TimeLimit:     yamlConfig.Simulation.StopCondition.TimeLimit,
+			Conditions:    convertStopRules(yamlConfig),
+			Precision: models.PrecisionConfig{
+				Metrics:         yamlConfig.Simulation.StopCondition.Precision.Metrics,
+				Relative:        yamlConfig.Simulation.StopCondition.Precision.Relative,
+				Absolute:        yamlConfig.Simulation.StopCondition.Precision.Absolute,
+				MaxReplications: yamlConfig.Simulation.StopCondition.Precision.MaxReplications,
+				CheckInterval:   yamlConfig.Simulation.StopCondition.Precision.CheckInterval,
+			},
 		},
 		Visualization: models.VisualizationConfig{
 			Enabled:             yamlConfig.Simulation.Visualization.Enabled,
@@ -76,8 +240,113 @@ func convertToModel(yamlConfig *YAMLConfig) *models.SimulationConfig {
 			ProgressBarWidth:    yamlConfig.Simulation.Visualization.ProgressBarWidth,
 		},
 		Random: models.RandomConfig{
-			Seed:         yamlConfig.Simulation.Random.Seed,
-			Distribution: yamlConfig.Simulation.Random.Distribution,
+			Seed:                yamlConfig.Simulation.Random.Seed,
+			Distribution:        yamlConfig.Simulation.Random.Distribution,
+			ArrivalDistribution: yamlConfig.Simulation.Random.ArrivalDistribution,
+			ServiceDistribution: yamlConfig.Simulation.Random.ServiceDistribution,
+			ArrivalTrace:        yamlConfig.Simulation.Random.ArrivalTrace,
+		},
+		VarianceReduction: models.VarianceReductionConfig{
+			CommonRandomNumbers: yamlConfig.Simulation.VarianceReduction.CommonRandomNumbers,
+			Antithetic:          yamlConfig.Simulation.VarianceReduction.Antithetic,
+			Pairs:               yamlConfig.Simulation.VarianceReduction.Pairs,
+			Metric:              yamlConfig.Simulation.VarianceReduction.Metric,
+		},
+		ControlVariates: models.ControlVariatesConfig{
+			Enabled: yamlConfig.Simulation.ControlVariates.Enabled,
+			Batches: yamlConfig.Simulation.ControlVariates.Batches,
+		},
+		Warmup: models.WarmupConfig{
+			Time:              yamlConfig.Simulation.Warmup.Time,
+			Customers:         yamlConfig.Simulation.Warmup.Customers,
+			Method:            yamlConfig.Simulation.Warmup.Method,
+			PilotReplications: yamlConfig.Simulation.Warmup.PilotReplications,
+			WelchWindow:       yamlConfig.Simulation.Warmup.WelchWindow,
+		},
+		OutputAnalysis: models.OutputAnalysisConfig{
+			Method:          yamlConfig.Simulation.OutputAnalysis.Method,
+			Batches:         yamlConfig.Simulation.OutputAnalysis.Batches,
+			ConfidenceLevel: yamlConfig.Simulation.OutputAnalysis.ConfidenceLevel,
+		},
+		Regenerative: models.RegenerativeConfig{
+			Enabled: yamlConfig.Simulation.Regenerative.Enabled,
+		},
+		Quantiles: models.QuantilesConfig{
+			Levels:           yamlConfig.Simulation.Quantiles.Levels,
+			CIMethod:         yamlConfig.Simulation.Quantiles.CIMethod,
+			BootstrapSamples: yamlConfig.Simulation.Quantiles.BootstrapSamples,
+		},
+		Streaming: models.StreamingConfig{
+			Enabled:          yamlConfig.Simulation.Streaming.Enabled,
+			RelativeAccuracy: yamlConfig.Simulation.Streaming.RelativeAccuracy,
+		},
+		Analytic: models.AnalyticConfig{
+			Enabled: yamlConfig.Simulation.Analytic.Enabled,
+		},
+		Consistency: models.ConsistencyConfig{
+			Tolerance: yamlConfig.Simulation.Consistency.Tolerance,
+		},
+		Departures: models.DeparturesConfig{
+			Lags:       yamlConfig.Simulation.Departures.Lags,
+			ExportFile: yamlConfig.Simulation.Departures.ExportFile,
+		},
+		SLA: models.SLAConfig{
+			Targets: convertSLATargets(yamlConfig),
+		},
+		Cost: models.CostConfig{
+			WaitingCost:      yamlConfig.Simulation.Cost.WaitingCost,
+			HoldingCost:      yamlConfig.Simulation.Cost.HoldingCost,
+			ServerCost:       yamlConfig.Simulation.Cost.ServerCost,
+			ServiceCost:      yamlConfig.Simulation.Cost.ServiceCost,
+			RejectionPenalty: yamlConfig.Simulation.Cost.RejectionPenalty,
+			Revenue:          yamlConfig.Simulation.Cost.Revenue,
+		},
+		TimeSeries: models.TimeSeriesConfig{
+			Window:     yamlConfig.Simulation.TimeSeries.Window,
+			Step:       yamlConfig.Simulation.TimeSeries.Step,
+			ExportFile: yamlConfig.Simulation.TimeSeries.ExportFile,
+		},
+		Transient: models.TransientConfig{
+			Replications:     yamlConfig.Simulation.Transient.Replications,
+			InitialCustomers: yamlConfig.Simulation.Transient.InitialCustomers,
+			Times:            yamlConfig.Simulation.Transient.Times,
+			Step:             yamlConfig.Simulation.Transient.Step,
+			ExportFile:       yamlConfig.Simulation.Transient.ExportFile,
+		},
+		Sweep: models.SweepConfig{
+			Parameters: convertSweepParameters(yamlConfig),
+			Metrics:    yamlConfig.Simulation.Sweep.Metrics,
+			OutputFile: yamlConfig.Simulation.Sweep.OutputFile,
+		},
+		Design: models.DesignConfig{
+			Type:       yamlConfig.Simulation.Design.Type,
+			Resolution: yamlConfig.Simulation.Design.Resolution,
+			Samples:    yamlConfig.Simulation.Design.Samples,
+			Factors:    convertDesignFactors(yamlConfig),
+			Responses:  yamlConfig.Simulation.Design.Responses,
+			OutputFile: yamlConfig.Simulation.Design.OutputFile,
+		},
+		Optimize: models.OptimizeConfig{
+			Field:           yamlConfig.Simulation.Optimize.Field,
+			Values:          yamlConfig.Simulation.Optimize.Values,
+			Range:           yamlConfig.Simulation.Optimize.Range,
+			CostMetric:      yamlConfig.Simulation.Optimize.CostMetric,
+			Constraints:     convertOptimizeConstraints(yamlConfig),
+			MaxReplications: yamlConfig.Simulation.Optimize.MaxReplications,
+		},
+		Selection: models.SelectionConfig{
+			Metric:              yamlConfig.Simulation.Selection.Metric,
+			Goal:                yamlConfig.Simulation.Selection.Goal,
+			Indifference:        yamlConfig.Simulation.Selection.Indifference,
+			Probability:         yamlConfig.Simulation.Selection.Probability,
+			InitialReplications: yamlConfig.Simulation.Selection.InitialReplications,
+			MaxReplications:     yamlConfig.Simulation.Selection.MaxReplications,
+			CommonRandomNumbers: yamlConfig.Simulation.Selection.CommonRandomNumbers,
+		},
+		Metamodel: models.MetamodelConfig{
+			Response:   yamlConfig.Simulation.Metamodel.Response,
+			Degree:     yamlConfig.Simulation.Metamodel.Degree,
+			OutputFile: yamlConfig.Simulation.Metamodel.OutputFile,
 		},
 		Logging: models.LoggingConfig{
 			Level:        yamlConfig.Simulation.Logging.Level,
@@ -87,3 +356,62 @@ func convertToModel(yamlConfig *YAMLConfig) *models.SimulationConfig {
 		},
 	}
 }
+
+func convertSLATargets(yamlConfig *YAMLConfig) []models.SLATarget {
+	targets := make([]models.SLATarget, len(yamlConfig.Simulation.SLA.Targets))
+	for i, target := range yamlConfig.Simulation.SLA.Targets {
+		targets[i] = models.SLATarget{
+			Name:      target.Name,
+			Metric:    target.Metric,
+			Type:      target.Type,
+			Threshold: target.Threshold,
+			Target:    target.Target,
+		}
+	}
+	return targets
+}
+
+func convertSweepParameters(yamlConfig *YAMLConfig) []models.SweepParameter {
+	parameters := make([]models.SweepParameter, len(yamlConfig.Simulation.Sweep.Parameters))
+	for i, parameter := range yamlConfig.Simulation.Sweep.Parameters {
+		parameters[i] = models.SweepParameter{
+			Field:  parameter.Field,
+			Values: parameter.Values,
+			Range:  parameter.Range,
+		}
+	}
+	return parameters
+}
+
+func convertDesignFactors(yamlConfig *YAMLConfig) []models.DesignFactor {
+	factors := make([]models.DesignFactor, len(yamlConfig.Simulation.Design.Factors))
+	for i, factor := range yamlConfig.Simulation.Design.Factors {
+		factors[i] = models.DesignFactor{
+			Field:   factor.Field,
+			Low:     factor.Low,
+			High:    factor.High,
+			Integer: factor.Integer,
+		}
+	}
+	return factors
+}
+
+func convertOptimizeConstraints(yamlConfig *YAMLConfig) []models.OptimizeConstraint {
+	constraints := make([]models.OptimizeConstraint, len(yamlConfig.Simulation.Optimize.Constraints))
+	for i, constraint := range yamlConfig.Simulation.Optimize.Constraints {
+		constraints[i] = models.OptimizeConstraint{
+			Metric: constraint.Metric,
+			Type:   constraint.Type,
+			Limit:  constraint.Limit,
+		}
+	}
+	return constraints
+}
+
+func convertStopRules(yamlConfig *YAMLConfig) []models.StopRule {
+	rules := make([]models.StopRule, len(yamlConfig.Simulation.StopCondition.Conditions))
+	for i, rule := range yamlConfig.Simulation.StopCondition.Conditions {
+		rules[i] = models.StopRule{Type: rule.Type, Value: rule.Value}
+	}
+	return rules
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const maxSweepPoints = 100000
+
+// SweepGrid expands every sweep parameter into its values and returns the
+// full grid, one list of settings per point. The first parameter varies
+// slowest.
+func SweepGrid(sweep models.SweepConfig) ([][]models.SweepSetting, error) {
+	if len(sweep.Parameters) == 0 {
+		return nil, fmt.Errorf("sweep lists no parameters")
+	}
+	grid := [][]models.SweepSetting{nil}
+	seen := make(map[string]bool)
+	for _, parameter := range sweep.Parameters {
+		if err := checkField(parameter.Field); err != nil {
+			return nil, err
+		}
+		if seen[parameter.Field] {
+			return nil, fmt.Errorf("field %q is swept twice", parameter.Field)
+		}
+		seen[parameter.Field] = true
+
+		values, err := sweepValues(parameter)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", parameter.Field, err)
+		}
+		if len(grid)*len(values) > maxSweepPoints {
+			return nil, fmt.Errorf("sweep has more than %d points", maxSweepPoints)
+		}
+		var expanded [][]models.SweepSetting
+		for _, point := range grid {
+			for _, value := range values {
+				settings := append(append([]models.SweepSetting{}, point...), models.SweepSetting{Field: parameter.Field, Value: value})
+				expanded = append(expanded, settings)
+			}
+		}
+		grid = expanded
+	}
+	return grid, nil
+}
+
+// sweepValues returns the listed values followed by those of the range.
+func sweepValues(parameter models.SweepParameter) ([]string, error) {
+	values := append([]string{}, parameter.Values...)
+	if parameter.Range != "" {
+		expanded, err := ParseRange(parameter.Range)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, expanded...)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values or range")
+	}
+	return values, nil
+}
+
+// ParseRange expands "low..high step s" (step 1 when omitted) into the values
+// low, low+s, ... up to and including high.
+func ParseRange(text string) ([]string, error) {
+	bounds, stepText, hasStep := strings.Cut(text, "step")
+	low, high, ok := strings.Cut(bounds, "..")
+	if !ok {
+		return nil, fmt.Errorf("range %q is not of the form low..high [step s]", text)
+	}
+	lo, err := strconv.ParseFloat(strings.TrimSpace(low), 64)
+	if err != nil {
+		return nil, fmt.Errorf("range %q: %v", text, err)
+	}
+	hi, err := strconv.ParseFloat(strings.TrimSpace(high), 64)
+	if err != nil {
+		return nil, fmt.Errorf("range %q: %v", text, err)
+	}
+	step := 1.0
+	if hasStep {
+		if step, err = strconv.ParseFloat(strings.TrimSpace(stepText), 64); err != nil {
+			return nil, fmt.Errorf("range %q: %v", text, err)
+		}
+	}
+	if step <= 0 || hi < lo {
+		return nil, fmt.Errorf("range %q needs low <= high and a positive step", text)
+	}
+
+	count := int(math.Floor((hi-lo)/step+1e-9)) + 1
+	if count > maxSweepPoints {
+		return nil, fmt.Errorf("range %q has more than %d values", text, maxSweepPoints)
+	}
+	values := make([]string, count)
+	for i := range values {
+		// 12 significant digits hide the rounding of lo + i*step
+		values[i] = strconv.FormatFloat(lo+float64(i)*step, 'g', 12, 64)
+	}
+	return values, nil
+}
+
+// checkField verifies that a dotted field such as "warmup.time" names a
+// value in the simulation section of the configuration file.
+func checkField(field string) error {
+	t := reflect.TypeOf(YAMLConfig{}.Simulation)
+	for _, name := range strings.Split(field, ".") {
+		if t.Kind() != reflect.Struct {
+			return fmt.Errorf("unknown configuration field %q", field)
+		}
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			if strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0] == name {
+				t, found = t.Field(i).Type, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown configuration field %q", field)
+		}
+	}
+	if t.Kind() == reflect.Struct {
+		return fmt.Errorf("configuration field %q is a section, not a value", field)
+	}
+	return nil
+}
+
+// LoadConfigWith loads filename with the given fields replaced. Each value is
+// parsed as YAML, so lists such as "[0.5, 0.9]" can be set as well.
+func LoadConfigWith(filename string, settings []models.SweepSetting) (*models.SimulationConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %v", err)
+	}
+	if len(document.Content) == 0 {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+
+	for _, setting := range settings {
+		if err := checkField(setting.Field); err != nil {
+			return nil, err
+		}
+		var value yaml.Node
+		if err := yaml.Unmarshal([]byte(setting.Value), &value); err != nil || len(value.Content) == 0 {
+			return nil, fmt.Errorf("invalid value %q for %s", setting.Value, setting.Field)
+		}
+		node := document.Content[0]
+		for _, key := range append([]string{"simulation"}, strings.Split(setting.Field, ".")...) {
+			node = mappingValue(node, key)
+		}
+		*node = *value.Content[0]
+	}
+
+	var yamlConfig YAMLConfig
+	if err := document.Decode(&yamlConfig); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %v", describeSettings(settings), err)
+	}
+	return finishConfig(&yamlConfig)
+}
+
+// mappingValue returns the value node of key in a mapping node, adding the
+// key with an empty mapping when it is missing.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		*mapping = yaml.Node{Kind: yaml.MappingNode}
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+func describeSettings(settings []models.SweepSetting) string {
+	parts := make([]string, len(settings))
+	for i, setting := range settings {
+		parts[i] = setting.Field + "=" + setting.Value
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"des/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"1..3", []string{"1", "2", "3"}},
+		{"0.5..1.9 step 0.1", []string{"0.5", "0.6", "0.7", "0.8", "0.9", "1", "1.1", "1.2", "1.3", "1.4", "1.5", "1.6", "1.7", "1.8", "1.9"}},
+		{"1..2 step 0.4", []string{"1", "1.4", "1.8"}},
+		{" 2 .. 2 ", []string{"2"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.text)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRange(%q) = %v, %v; want %v", tt.text, got, err, tt.want)
+		}
+	}
+	for _, text := range []string{"1-3", "3..1", "1..3 step 0", "1..3 step -1", "a..3", "1..3 step x"} {
+		if got, err := ParseRange(text); err == nil {
+			t.Errorf("ParseRange(%q) = %v, want an error", text, got)
+		}
+	}
+}
+
+func TestSweepGrid(t *testing.T) {
+	grid, err := SweepGrid(models.SweepConfig{Parameters: []models.SweepParameter{
+		{Field: "servers", Values: []string{"1", "2"}},
+		{Field: "arrival_rate", Values: []string{"0.2"}, Range: "0.5..0.6 step 0.1"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first parameter varies slowest; listed values precede the range
+	var got [][2]string
+	for _, point := range grid {
+		if len(point) != 2 || point[0].Field != "servers" || point[1].Field != "arrival_rate" {
+			t.Fatalf("point %v, want servers then arrival_rate", point)
+		}
+		got = append(got, [2]string{point[0].Value, point[1].Value})
+	}
+	want := [][2]string{{"1", "0.2"}, {"1", "0.5"}, {"1", "0.6"}, {"2", "0.2"}, {"2", "0.5"}, {"2", "0.6"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("grid %v, want %v", got, want)
+	}
+
+	for _, parameters := range [][]models.SweepParameter{
+		nil,
+		{{Field: "servers", Values: []string{"1"}}, {Field: "servers", Values: []string{"2"}}},
+		{{Field: "no_such_field", Values: []string{"1"}}},
+		{{Field: "warmup", Values: []string{"1"}}},
+		{{Field: "warmup.time"}},
+		{{Field: "arrival_rate", Range: "2..1"}},
+	} {
+		if _, err := SweepGrid(models.SweepConfig{Parameters: parameters}); err == nil {
+			t.Errorf("SweepGrid(%v) succeeded, want an error", parameters)
+		}
+	}
+}
+
+func TestLoadConfigWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	base := "simulation:\n  simulation_time: 100\n  arrival_rate: 1\n  service_rate: 2\n  warmup:\n    time: 5\n"
+	if err := os.WriteFile(path, []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// existing values are replaced and missing sections are created
+	config, err := LoadConfigWith(path, []models.SweepSetting{
+		{Field: "arrival_rate", Value: "0.9"},
+		{Field: "warmup.time", Value: "50"},
+		{Field: "random.seed", Value: "7"},
+		{Field: "quantiles.levels", Value: "[0.5, 0.9]"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ArrivalRate != 0.9 || config.Warmup.Time != 50 || config.Random.Seed != 7 ||
+		!reflect.DeepEqual(config.Quantiles.Levels, []float64{0.5, 0.9}) {
+		t.Errorf("arrival rate %g, warm-up %g, seed %d, levels %v; want 0.9, 50, 7, [0.5 0.9]",
+			config.ArrivalRate, config.Warmup.Time, config.Random.Seed, config.Quantiles.Levels)
+	}
+	if config.SimulationTime != 100 || config.ServiceRate != 2 {
+		t.Errorf("simulation time %g, service rate %g; the other fields must keep their values", config.SimulationTime, config.ServiceRate)
+	}
+
+	for _, setting := range []models.SweepSetting{
+		{Field: "arrival_rate", Value: "fast"},
+		{Field: "arrival_rate", Value: "[1"},
+		{Field: "no_such_field", Value: "1"},
+	} {
+		if _, err := LoadConfigWith(path, []models.SweepSetting{setting}); err == nil {
+			t.Errorf("LoadConfigWith(%s=%s) succeeded, want an error", setting.Field, setting.Value)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadArrivalTrace reads absolute arrival timestamps, one per line, as
+// written by the departure export. The first field of each line is used;
+// blank lines, comments and a non-numeric header line are skipped.
+func loadArrivalTrace(filename string) ([]float64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read arrival trace: %v", err)
+	}
+	defer file.Close()
+
+	var times []float64
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		field := strings.TrimSpace(strings.Split(text, ",")[0])
+		t, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			if len(times) == 0 && line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("arrival trace line %d: %v", line, err)
+		}
+		if len(times) > 0 && t < times[len(times)-1] {
+			return nil, fmt.Errorf("arrival trace line %d: timestamps must not decrease", line)
+		}
+		times = append(times, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read arrival trace: %v", err)
+	}
+	if len(times) == 0 {
+		return nil, fmt.Errorf("arrival trace %s is empty", filename)
+	}
+	return times, nil
+}
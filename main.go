@@ -14,8 +14,29 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sweep":
+			runSweepCommand(os.Args[2:])
+			return
+		case "design":
+			runDesignCommand(os.Args[2:])
+			return
+		case "optimize":
+			runOptimizeCommand(os.Args[2:])
+			return
+		case "compare":
+			runCompareCommand(os.Args[2:])
+			return
+		case "predict":
+			runPredictCommand(os.Args[2:])
+			return
+		}
+	}
+
 	configFile := flag.String("config", "config.yml", "Path to configuration file")
-	runMode := flag.String("mode", "automatic", "Run mode: automatic or manual")
+	runMode := flag.String("mode", "automatic", "Run mode: automatic, manual or transient")
+	compareFile := flag.String("compare", "", "Path to a second configuration to compare against")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configFile)
@@ -24,32 +45,56 @@ func main() {
 		os.Exit(1)
 	}
 
-	var automaticValue bool
+	var automaticValue, transientMode bool
 	switch strings.ToLower(*runMode) {
 	case "automatic", "a":
 		automaticValue = true
 	case "manual", "m":
 		automaticValue = false
+	case "transient", "t":
+		transientMode = true
 	default:
-		fmt.Printf("Invalid mode: %s\nValid options: automatic (a), manual (m)\n", *runMode)
+		fmt.Printf("Invalid mode: %s\nValid options: automatic (a), manual (m), transient (t)\n", *runMode)
 		os.Exit(1)
 	}
 
-	logger := logging.NewLogger(
-		cfg.Logging.Level,
-		cfg.Logging.LogToFile,
-		cfg.Logging.LogFilePath,
-		cfg.Logging.OutputFormat,
-	)
+	if err := validateConfig(cfg); err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger := newLogger(cfg)
+
+	if *compareFile != "" {
+		runComparison(logger, cfg, *compareFile)
+		return
+	}
+
+	if transientMode {
+		if err := simulation.ValidateTransient(cfg); err != nil {
+			fmt.Printf("Invalid transient analysis: %v\n", err)
+			os.Exit(1)
+		}
+		runTransient(logger, cfg)
+		return
+	}
 
 	initializeSimulation(logger, cfg)
 
+	cfg.StopCondition.AutomaticMode = automaticValue
+
+	if cfg.StopCondition.AutomaticMode && cfg.Replications > 1 {
+		runReplications(logger, cfg)
+		logger.LogInfo("Simulation completed successfully")
+		return
+	}
+
+	// only a single run needs this simulator, and initializing it runs the
+	// warm-up pilots and opens the departure export
 	simulator := simulation.NewSimulator(cfg)
 	simulator.GetVisualizer().SetLogger(logger)
 	simulator.Initialize()
 
-	cfg.StopCondition.AutomaticMode = automaticValue
-
 	if cfg.StopCondition.AutomaticMode {
 		runAutomaticSimulation(simulator, logger, cfg)
 	} else {
@@ -59,6 +104,45 @@ func main() {
 	logger.LogInfo("Simulation completed successfully")
 }
 
+func newLogger(cfg *models.SimulationConfig) *logging.Logger {
+	return logging.NewLogger(
+		cfg.Logging.Level,
+		cfg.Logging.LogToFile,
+		cfg.Logging.LogFilePath,
+		cfg.Logging.OutputFormat,
+	)
+}
+
+// progressReporter logs the number of completed runs at every tenth of the
+// total.
+func progressReporter(logger *logging.Logger, label string) simulation.ProgressFunc {
+	return func(done, total int) {
+		if done == total || done*10/total != (done-1)*10/total {
+			logger.LogInfo(fmt.Sprintf("%s: %d/%d runs done", label, done, total))
+		}
+	}
+}
+
+// validateConfig checks the parts of a configuration that a run needs.
+func validateConfig(cfg *models.SimulationConfig) error {
+	if err := simulation.ValidateStopCondition(cfg); err != nil {
+		return fmt.Errorf("invalid stop condition: %v", err)
+	}
+	if err := simulation.ValidateQuantiles(cfg); err != nil {
+		return fmt.Errorf("invalid quantiles: %v", err)
+	}
+	if err := simulation.ValidateSLA(cfg); err != nil {
+		return fmt.Errorf("invalid SLA targets: %v", err)
+	}
+	if err := simulation.ValidateTimeSeries(cfg); err != nil {
+		return fmt.Errorf("invalid time series: %v", err)
+	}
+	if cfg.Streaming.Enabled && cfg.ControlVariates.Enabled {
+		return fmt.Errorf("control_variates need the per-customer data that streaming does not keep; disable one of them")
+	}
+	return nil
+}
+
 func initializeSimulation(logger *logging.Logger, cfg *models.SimulationConfig) {
 	logger.LogInfo("=== DISCRETE EVENT SIMULATION INITIALIZATION ===")
 	logger.LogInfo(fmt.Sprintf("Automatic Mode: %v", cfg.StopCondition.AutomaticMode))
@@ -67,9 +151,325 @@ func initializeSimulation(logger *logging.Logger, cfg *models.SimulationConfig)
 	logger.LogInfo(fmt.Sprintf("Service Rate: %.2f", cfg.ServiceRate))
 	logger.LogInfo(fmt.Sprintf("Max Queue Size: %d", cfg.MaxQueueSize))
 	logger.LogInfo(fmt.Sprintf("Max Customers: %d", cfg.MaxCustomers))
+	logger.LogInfo(fmt.Sprintf("Replications: %d", cfg.Replications))
 	logger.LogInfo(fmt.Sprintf("Stop Condition: %s", cfg.StopCondition.Type))
 }
 
+// runSweepCommand runs the grid of the sweep section: sim sweep [-config
+// file] [-out file].
+func runSweepCommand(args []string) {
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	configFile := flags.String("config", "config.yml", "Path to configuration file")
+	outputFile := flags.String("out", "", "Write the sweep table to this CSV or JSON file (overrides sweep.output_file)")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Printf("Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	grid, err := config.SweepGrid(cfg.Sweep)
+	if err != nil {
+		fmt.Printf("Invalid sweep: %v\n", err)
+		os.Exit(1)
+	}
+	metrics, err := simulation.SweepMetrics(cfg)
+	if err != nil {
+		fmt.Printf("Invalid sweep: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Metamodel.Response != "" {
+		if err := simulation.ValidateMetamodel(cfg, metrics); err != nil {
+			fmt.Printf("Invalid metamodel: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	points := loadSweepPoints(*configFile, grid)
+	logger := newLogger(cfg)
+	logger.LogInfo(fmt.Sprintf("Starting sweep over %d points on %d workers", len(points), simulation.Workers(cfg)))
+	result := simulation.RunSweep(cfg, points, metrics, progressReporter(logger, "Sweep"))
+
+	path := cfg.Sweep.OutputFile
+	if *outputFile != "" {
+		path = *outputFile
+	}
+	if path != "" {
+		if err := simulation.WriteSweep(path, result); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+	visualizer.DisplaySweep(result)
+	fitMetamodel(logger, visualizer, cfg, result)
+}
+
+// runDesignCommand runs the experiment of the design section and estimates
+// the factor effects: sim design [-config file] [-out file].
+func runDesignCommand(args []string) {
+	flags := flag.NewFlagSet("design", flag.ExitOnError)
+	configFile := flags.String("config", "config.yml", "Path to configuration file")
+	outputFile := flags.String("out", "", "Write the design table to this CSV or JSON file (overrides design.output_file)")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Printf("Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if err := simulation.ValidateDesign(cfg); err != nil {
+		fmt.Printf("Invalid design: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Metamodel.Response != "" {
+		if err := simulation.ValidateMetamodel(cfg, cfg.Design.Responses); err != nil {
+			fmt.Printf("Invalid metamodel: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	design := simulation.BuildDesign(cfg)
+	points := loadSweepPoints(*configFile, design.Settings)
+	logger := newLogger(cfg)
+	logger.LogInfo(fmt.Sprintf("Starting %s design with %d runs on %d workers", design.Type, len(points), simulation.Workers(cfg)))
+	design.Sweep = simulation.RunSweep(cfg, points, cfg.Design.Responses, progressReporter(logger, "Design"))
+	simulation.AnalyzeDesign(design, design.Sweep.ConfidenceLevel)
+
+	path := cfg.Design.OutputFile
+	if *outputFile != "" {
+		path = *outputFile
+	}
+	if path != "" {
+		if err := simulation.WriteSweep(path, design.Sweep); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+	visualizer.DisplaySweep(design.Sweep)
+	visualizer.DisplayDesign(design)
+	fitMetamodel(logger, visualizer, cfg, design.Sweep)
+}
+
+// fitMetamodel fits and saves the metamodel section's response surface over
+// the points of a sweep or design, if a response is configured.
+func fitMetamodel(logger *logging.Logger, visualizer *simulation.TerminalVisualizer, cfg *models.SimulationConfig, result *models.SweepResult) {
+	if cfg.Metamodel.Response == "" {
+		return
+	}
+	model, err := simulation.FitMetamodel(cfg, result)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Metamodel not fitted: %v", err))
+		return
+	}
+	if cfg.Metamodel.OutputFile != "" {
+		if err := simulation.WriteMetamodel(cfg.Metamodel.OutputFile, model); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+	visualizer.DisplayMetamodel(model)
+}
+
+// runPredictCommand evaluates a saved metamodel at the settings given as
+// field=value arguments: sim predict [-model file] field=value ...
+func runPredictCommand(args []string) {
+	flags := flag.NewFlagSet("predict", flag.ExitOnError)
+	modelFile := flags.String("model", "metamodel.json", "Path to a metamodel saved by sweep or design")
+	flags.Parse(args)
+
+	model, err := simulation.ReadMetamodel(*modelFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var settings []models.SweepSetting
+	for _, arg := range flags.Args() {
+		field, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			fmt.Printf("Invalid setting %q, expected field=value\n", arg)
+			os.Exit(1)
+		}
+		settings = append(settings, models.SweepSetting{Field: field, Value: value})
+	}
+	prediction, err := simulation.Predict(model, settings)
+	if err != nil {
+		fmt.Printf("Prediction failed: %v\n", err)
+		os.Exit(1)
+	}
+	simulation.NewTerminalVisualizer().DisplayPrediction(prediction)
+}
+
+// runOptimizeCommand searches the optimize section's decision variable for
+// the cheapest feasible value: sim optimize [-config file].
+func runOptimizeCommand(args []string) {
+	flags := flag.NewFlagSet("optimize", flag.ExitOnError)
+	configFile := flags.String("config", "config.yml", "Path to configuration file")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Printf("Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if err := simulation.ValidateOptimize(cfg); err != nil {
+		fmt.Printf("Invalid optimization: %v\n", err)
+		os.Exit(1)
+	}
+	grid, err := config.SweepGrid(models.SweepConfig{Parameters: []models.SweepParameter{{
+		Field:  cfg.Optimize.Field,
+		Values: cfg.Optimize.Values,
+		Range:  cfg.Optimize.Range,
+	}}})
+	if err != nil {
+		fmt.Printf("Invalid optimization: %v\n", err)
+		os.Exit(1)
+	}
+
+	points := loadSweepPoints(*configFile, grid)
+	logger := newLogger(cfg)
+	logger.LogInfo(fmt.Sprintf("Searching %d candidate values of %s", len(points), cfg.Optimize.Field))
+	result, err := simulation.Optimize(cfg, points, func(evaluated, total int) {
+		logger.LogInfo(fmt.Sprintf("Optimization: %d of %d candidates evaluated", evaluated, total))
+	})
+	if err != nil {
+		fmt.Printf("Optimization failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+	visualizer.DisplayOptimization(result)
+}
+
+// loadSweepPoints loads configFile once per point of the grid, with the
+// point's settings applied, and exits if any point is invalid.
+func loadSweepPoints(configFile string, grid [][]models.SweepSetting) []*models.SweepPoint {
+	points := make([]*models.SweepPoint, len(grid))
+	for i, settings := range grid {
+		pointCfg, err := config.LoadConfigWith(configFile, settings)
+		if err == nil {
+			err = validateConfig(pointCfg)
+		}
+		if err != nil {
+			fmt.Printf("Point %d: %v\n", i+1, err)
+			os.Exit(1)
+		}
+		points[i] = &models.SweepPoint{Settings: settings, Config: pointCfg}
+	}
+	return points
+}
+
+// runCompareCommand compares the configurations given as arguments. Two are
+// compared by the interval of their difference, more by ranking and
+// selection. The settings come from the first configuration.
+func runCompareCommand(args []string) {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	flags.Parse(args)
+	files := flags.Args()
+	if len(files) < 2 {
+		fmt.Println("Usage: compare <config> <config> [<config> ...]")
+		os.Exit(1)
+	}
+
+	configs := make([]*models.SimulationConfig, len(files))
+	for i, file := range files {
+		cfg, err := config.LoadConfig(file)
+		if err == nil {
+			err = validateConfig(cfg)
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", file, err)
+			os.Exit(1)
+		}
+		configs[i] = cfg
+	}
+	logger := newLogger(configs[0])
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+
+	if len(configs) == 2 {
+		logger.LogInfo(fmt.Sprintf("Comparing %s (A) with %s (B) over %d pairs", files[0], files[1], configs[0].VarianceReduction.Pairs))
+		comparison, err := simulation.ComparePaired(configs[0], configs[1], configs[0].VarianceReduction, progressReporter(logger, "Comparison"))
+		if err != nil {
+			fmt.Printf("Comparison failed: %v\n", err)
+			os.Exit(1)
+		}
+		comparison.NameA, comparison.NameB = files[0], files[1]
+		visualizer.DisplayComparison(comparison)
+		return
+	}
+
+	if err := simulation.ValidateSelection(configs[0]); err != nil {
+		fmt.Printf("Invalid selection: %v\n", err)
+		os.Exit(1)
+	}
+	logger.LogInfo(fmt.Sprintf("Selecting the best of %d systems by %s", len(configs), configs[0].Selection.Metric))
+	result, err := simulation.SelectBest(configs, files, func(replications, budget int) {
+		if replications%10 == 0 {
+			logger.LogInfo(fmt.Sprintf("Selection: %d replications per surviving system (limit %d)", replications, budget))
+		}
+	})
+	if err != nil {
+		fmt.Printf("Selection failed: %v\n", err)
+		os.Exit(1)
+	}
+	visualizer.DisplaySelection(result)
+}
+
+func runComparison(logger *logging.Logger, cfg *models.SimulationConfig, compareFile string) {
+	other, err := config.LoadConfig(compareFile)
+	if err == nil {
+		err = validateConfig(other)
+	}
+	if err != nil {
+		fmt.Printf("Invalid comparison configuration %s: %v\n", compareFile, err)
+		os.Exit(1)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Comparing base configuration (A) with %s (B) over %d pairs", compareFile, cfg.VarianceReduction.Pairs))
+	comparison, err := simulation.ComparePaired(cfg, other, cfg.VarianceReduction, progressReporter(logger, "Comparison"))
+	if err != nil {
+		fmt.Printf("Comparison failed: %v\n", err)
+		os.Exit(1)
+	}
+	comparison.NameA, comparison.NameB = "base configuration", compareFile
+
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+	visualizer.DisplayComparison(comparison)
+}
+
+func runReplications(logger *logging.Logger, cfg *models.SimulationConfig) {
+	logger.LogInfo(fmt.Sprintf("Starting %d independent replications on %d workers", cfg.Replications, simulation.Workers(cfg)))
+	var summary *models.ReplicationSummary
+	if cfg.StopCondition.Type == "precision" {
+		summary = simulation.RunReplicationsToPrecision(cfg, progressReporter(logger, "Replications"))
+	} else {
+		summary = simulation.RunReplications(cfg, cfg.Replications, progressReporter(logger, "Replications"))
+	}
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+	visualizer.DisplayReplications(summary)
+}
+
+func runTransient(logger *logging.Logger, cfg *models.SimulationConfig) {
+	logger.LogInfo(fmt.Sprintf("Starting transient analysis over %d replications from %d initial customers",
+		cfg.Transient.Replications, cfg.Transient.InitialCustomers))
+	analysis := simulation.RunTransient(cfg, progressReporter(logger, "Transient analysis"))
+	if cfg.Transient.ExportFile != "" {
+		if err := simulation.WriteTransient(cfg.Transient.ExportFile, analysis); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+
+	visualizer := simulation.NewTerminalVisualizer()
+	visualizer.SetLogger(logger)
+	visualizer.DisplayTransient(analysis)
+}
+
 func runAutomaticSimulation(simulator *simulation.DiscreteEventSimulator, logger *logging.Logger, cfg *models.SimulationConfig) {
 	logger.LogInfo("Starting simulation in AUTOMATIC mode")
 	if simulator.GetState().Clock == 0 && simulator.GetEvents().PeekNextEvent() == nil {
@@ -84,22 +484,10 @@ func runManualSimulation(simulator *simulation.DiscreteEventSimulator, logger *l
 	logger.LogInfo("Starting simulation in MANUAL mode")
 	logger.LogInfo("Press ENTER key to advance to next event")
 
-	simulator.Initialize()
 	scanner := bufio.NewScanner(os.Stdin)
 
-	for {
-		if simulator.ShouldStop() {
-			break
-		}
-
+	for !simulator.ShouldStop() {
 		nextEvent := simulator.GetEvents().PeekNextEvent()
-		if nextEvent == nil {
-			break
-		}
-
-		if nextEvent.Timestamp > cfg.SimulationTime {
-			break
-		}
 
 		simulator.GetVisualizer().ClearScreen()
 		simulator.GetVisualizer().DisplayHeader(cfg)
@@ -111,43 +499,13 @@ func runManualSimulation(simulator *simulation.DiscreteEventSimulator, logger *l
 
 		if input == "q" || input == "Q" {
 			logger.LogInfo("Manual simulation stopped by user")
+			simulator.Stop(simulation.StopReasonUser)
 			break
 		}
 
-		event := simulator.GetEvents().GetNextEvent()
-		if event == nil {
-			break
-		}
-
-		timeDiff := event.Timestamp - simulator.GetState().LastEventTime
-		if timeDiff > 0 {
-			simulator.GetStats().UpdatePreEvent(simulator.GetState(), timeDiff)
-		}
-
-		simulator.GetState().Clock = event.Timestamp
-		simulator.ProcessEvent(event)
-
-		if simulator.ShouldStop() {
-			break
-		}
-	}
-
-	if simulator.GetState().Clock < cfg.SimulationTime {
-		finalTimeDiff := cfg.SimulationTime - simulator.GetState().LastEventTime
-		if finalTimeDiff > 0 {
-			simulator.GetStats().UpdatePreEvent(simulator.GetState(), finalTimeDiff)
-		}
-		simulator.GetState().Clock = cfg.SimulationTime
-	}
-
-	metrics := simulator.GetStats().CalculateFinalMetrics(simulator.GetState())
-	results := &models.SimulationResults{
-		Config:   cfg,
-		Metrics:  metrics,
-		State:    simulator.GetState(),
-		Runtime:  time.Duration(0),
-		EventLog: simulator.GetEventLog(),
+		simulator.ProcessEvent(simulator.GetEvents().GetNextEvent())
 	}
 
+	results := simulator.Finish(time.Duration(0))
 	simulator.GetVisualizer().DisplayResults(results, cfg)
 }
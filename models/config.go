@@ -5,8 +5,23 @@ import "time"
 type StopCondition struct {
 	AutomaticMode bool
 	Type          string
-	Value         int
+	Value         float64
 	TimeLimit     float64
+	Conditions    []StopRule
+	Precision     PrecisionConfig
+}
+
+type StopRule struct {
+	Type  string
+	Value float64
+}
+
+type PrecisionConfig struct {
+	Metrics         []string
+	Relative        float64
+	Absolute        float64
+	MaxReplications int
+	CheckInterval   float64
 }
 
 type VisualizationConfig struct {
@@ -17,8 +32,167 @@ type VisualizationConfig struct {
 }
 
 type RandomConfig struct {
-	Seed         int64
-	Distribution string
+	Seed                int64
+	Distribution        string
+	ArrivalDistribution string
+	ServiceDistribution string
+	ArrivalTrace        string
+	ArrivalTimes        []float64
+	Antithetic          bool
+}
+
+type VarianceReductionConfig struct {
+	CommonRandomNumbers bool
+	Antithetic          bool
+	Pairs               int
+	Metric              string
+}
+
+type ControlVariatesConfig struct {
+	Enabled bool
+	Batches int
+}
+
+type WarmupConfig struct {
+	Time              float64
+	Customers         int
+	Method            string
+	PilotReplications int
+	WelchWindow       int
+}
+
+type OutputAnalysisConfig struct {
+	Method          string
+	Batches         int
+	ConfidenceLevel float64
+}
+
+type RegenerativeConfig struct {
+	Enabled bool
+}
+
+type QuantilesConfig struct {
+	Levels           []float64
+	CIMethod         string
+	BootstrapSamples int
+}
+
+type DeparturesConfig struct {
+	Lags       int
+	ExportFile string
+}
+
+type SLATarget struct {
+	Name      string
+	Metric    string
+	Type      string
+	Threshold float64
+	Target    float64
+}
+
+type SLAConfig struct {
+	Targets []SLATarget
+}
+
+type TimeSeriesConfig struct {
+	Window     float64
+	Step       float64
+	ExportFile string
+}
+
+type TransientConfig struct {
+	Replications     int
+	InitialCustomers int
+	Times            []float64
+	Step             float64
+	ExportFile       string
+}
+
+type SweepParameter struct {
+	Field  string
+	Values []string
+	Range  string
+}
+
+type SweepConfig struct {
+	Parameters []SweepParameter
+	Metrics    []string
+	OutputFile string
+}
+
+// SweepSetting assigns one value to a configuration field
+type SweepSetting struct {
+	Field string
+	Value string
+}
+
+type DesignFactor struct {
+	Field   string
+	Low     float64
+	High    float64
+	Integer bool
+}
+
+type DesignConfig struct {
+	Type       string
+	Resolution int
+	Samples    int
+	Factors    []DesignFactor
+	Responses  []string
+	OutputFile string
+}
+
+type OptimizeConstraint struct {
+	Metric string
+	Type   string
+	Limit  float64
+}
+
+type OptimizeConfig struct {
+	Field           string
+	Values          []string
+	Range           string
+	CostMetric      string
+	Constraints     []OptimizeConstraint
+	MaxReplications int
+}
+
+type MetamodelConfig struct {
+	Response   string
+	Degree     int
+	OutputFile string
+}
+
+type SelectionConfig struct {
+	Metric              string
+	Goal                string
+	Indifference        float64
+	Probability         float64
+	InitialReplications int
+	MaxReplications     int
+	CommonRandomNumbers bool
+}
+
+type CostConfig struct {
+	WaitingCost      float64
+	HoldingCost      float64
+	ServerCost       float64
+	ServiceCost      float64
+	RejectionPenalty float64
+	Revenue          float64
+}
+
+type ConsistencyConfig struct {
+	Tolerance float64
+}
+
+type AnalyticConfig struct {
+	Enabled bool
+}
+
+type StreamingConfig struct {
+	Enabled          bool
+	RelativeAccuracy float64
 }
 
 type LoggingConfig struct {
@@ -29,13 +203,35 @@ type LoggingConfig struct {
 }
 
 type SimulationConfig struct {
-	SimulationTime float64
-	ArrivalRate    float64
-	ServiceRate    float64
-	MaxQueueSize   int
-	MaxCustomers   int
-	StopCondition  StopCondition
-	Visualization  VisualizationConfig
-	Random         RandomConfig
-	Logging        LoggingConfig
+	SimulationTime    float64
+	ArrivalRate       float64
+	ServiceRate       float64
+	MaxQueueSize      int
+	Servers           int
+	MaxCustomers      int
+	Replications      int
+	Parallelism       int
+	StopCondition     StopCondition
+	Visualization     VisualizationConfig
+	Random            RandomConfig
+	VarianceReduction VarianceReductionConfig
+	ControlVariates   ControlVariatesConfig
+	Warmup            WarmupConfig
+	OutputAnalysis    OutputAnalysisConfig
+	Regenerative      RegenerativeConfig
+	Quantiles         QuantilesConfig
+	Streaming         StreamingConfig
+	Analytic          AnalyticConfig
+	Consistency       ConsistencyConfig
+	Departures        DeparturesConfig
+	SLA               SLAConfig
+	Cost              CostConfig
+	TimeSeries        TimeSeriesConfig
+	Transient         TransientConfig
+	Sweep             SweepConfig
+	Design            DesignConfig
+	Optimize          OptimizeConfig
+	Selection         SelectionConfig
+	Metamodel         MetamodelConfig
+	Logging           LoggingConfig
 }
@@ -2,12 +2,13 @@ package models
 
 // Customer represents a customer in the system
 type Customer struct {
-	ID           int
-	ArrivalTime  float64
-	ServiceTime  float64
-	ServiceStart float64
-	ExitTime     float64
-	Status       CustomerStatus
+	ID               int
+	ArrivalTime      float64
+	InterarrivalTime float64
+	ServiceTime      float64
+	ServiceStart     float64
+	ExitTime         float64
+	Status           CustomerStatus
 }
 
 type CustomerStatus int
@@ -21,6 +22,7 @@ const (
 
 // CustomerStats holds statistics for a customer
 type CustomerStats struct {
+	CustomerID int
 	WaitTime   float64
 	SystemTime float64
 }
@@ -0,0 +1,277 @@
+package models
+
+// ScenarioComparison holds a paired comparison of two configurations
+type ScenarioComparison struct {
+	Metric               string
+	NameA                string
+	NameB                string
+	Method               string
+	Freedom              float64
+	Pairs                int
+	CommonRandomNumbers  bool
+	Antithetic           bool
+	ValuesA              []float64
+	ValuesB              []float64
+	MeanA                float64
+	MeanB                float64
+	MeanDifference       float64
+	ConfidenceLevel      float64
+	DifferenceConfidence [2]float64
+	DifferenceVariance   float64
+	IndependentVariance  float64
+	VarianceReduction    float64
+	AntitheticReductionA float64
+	AntitheticReductionB float64
+}
+
+// MetricSummary aggregates one metric across replications
+type MetricSummary struct {
+	Name       string
+	Label      string
+	Values     []float64
+	Mean       float64
+	StdDev     float64
+	Confidence [2]float64
+}
+
+// ReplicationSummary holds the results of independent replications
+type ReplicationSummary struct {
+	Replications    int
+	ConfidenceLevel float64
+	Seeds           []int64
+	Metrics         []*MetricSummary
+	Precision       *PrecisionReport
+	Theory          *TheoryValidation
+	SLA             []*SLAResult
+	Warnings        []string
+}
+
+// PrecisionReport records the precision achieved by a sequential stop
+type PrecisionReport struct {
+	Relative  float64
+	Absolute  float64
+	Converged bool
+	Budget    string
+	Metrics   []*PrecisionResult
+}
+
+// PrecisionResult is the achieved precision of one metric
+type PrecisionResult struct {
+	Metric            string
+	Estimate          float64
+	HalfWidth         float64
+	RelativeHalfWidth float64
+	Met               bool
+}
+
+// AnalyticModel holds the closed-form steady-state measures of a queue
+type AnalyticModel struct {
+	Name        string
+	L           float64
+	Lq          float64
+	W           float64
+	Wq          float64
+	Utilization float64
+	Blocking    float64
+	Throughput  float64
+}
+
+// TheoryValidation puts simulated values next to an analytic model
+type TheoryValidation struct {
+	Model           *AnalyticModel
+	Unavailable     string
+	ConfidenceLevel float64
+	Comparisons     []*TheoryComparison
+}
+
+// TheoryComparison compares one simulated metric with its analytic value
+type TheoryComparison struct {
+	Metric        string
+	Label         string
+	Analytic      float64
+	Simulated     float64
+	RelativeError float64
+	Confidence    [2]float64
+	HasConfidence bool
+	WithinCI      bool
+}
+
+// TransientAnalysis holds ensemble averages of the state at fixed times
+// across replications started from the same initial state
+type TransientAnalysis struct {
+	Replications     int
+	InitialCustomers int
+	ConfidenceLevel  float64
+	Points           []*TransientPoint
+	ExportFile       string
+}
+
+// TransientPoint is the ensemble estimate of the state at one time
+type TransientPoint struct {
+	Time        float64
+	QueueLength *EnsembleEstimate
+	InSystem    *EnsembleEstimate
+	Utilization *EnsembleEstimate
+	Wait        *EnsembleEstimate
+}
+
+// EnsembleEstimate is the mean of one quantity over the replications that
+// observed it
+type EnsembleEstimate struct {
+	Observations int
+	Mean         float64
+	StdDev       float64
+	Confidence   [2]float64
+}
+
+// SweepResult holds the metrics of every point of a parameter sweep
+type SweepResult struct {
+	Fields          []string
+	Metrics         []string
+	ConfidenceLevel float64
+	HasConfidence   bool
+	Points          []*SweepPoint
+	OutputFile      string
+}
+
+// SweepPoint is one combination of swept values and its metrics, in the
+// order of SweepResult.Metrics; a metric the point did not produce is nil
+type SweepPoint struct {
+	Settings     []SweepSetting
+	Config       *SimulationConfig
+	Replications int
+	Metrics      []*MetricSummary
+}
+
+// ExperimentDesign is a set of runs generated from factor definitions. Coded
+// holds the factor levels of each run scaled to [-1, 1]
+type ExperimentDesign struct {
+	Type       string
+	Resolution int
+	Generators []string
+	Factors    []DesignFactor
+	Coded      [][]float64
+	Settings   [][]SweepSetting
+	Sweep      *SweepResult
+	Effects    []*ResponseEffects
+}
+
+// ResponseEffects holds the effect estimates of the factors on one response
+type ResponseEffects struct {
+	Response string
+	Effects  []*EffectEstimate
+	RSquared float64
+	Points   int
+	Freedom  int
+	Unfitted string
+}
+
+// EffectEstimate is the change in a response when its factors (one for a main
+// effect, two for an interaction) move from their low to their high level.
+// Aliases lists the interactions that cannot be told apart from it
+type EffectEstimate struct {
+	Term          string
+	Aliases       []string
+	Effect        float64
+	StdError      float64
+	Confidence    [2]float64
+	HasConfidence bool
+}
+
+// OptimizationResult records a search for the cheapest feasible value of one
+// decision variable
+type OptimizationResult struct {
+	Field           string
+	CostMetric      string
+	ConfidenceLevel float64
+	Candidates      []*OptimizationCandidate
+	Best            *OptimizationCandidate
+}
+
+// OptimizationCandidate is one evaluated value of the decision variable
+type OptimizationCandidate struct {
+	Value        string
+	Replications int
+	Feasible     bool
+	Conclusive   bool
+	Constraints  []*ConstraintCheck
+	Cost         *MetricSummary
+}
+
+// ConstraintCheck compares the interval of one metric with its limit
+type ConstraintCheck struct {
+	Metric     string
+	Type       string
+	Limit      float64
+	Estimate   float64
+	Confidence [2]float64
+	Met        bool
+	Conclusive bool
+}
+
+// SelectionResult records a ranking-and-selection run over several systems
+type SelectionResult struct {
+	Metric              string
+	Goal                string
+	Indifference        float64
+	Probability         float64
+	InitialReplications int
+	CommonRandomNumbers bool
+	Systems             []*SelectedSystem
+	Best                *SelectedSystem
+	Truncated           bool
+}
+
+// SelectedSystem is one system of a selection and how far it got
+type SelectedSystem struct {
+	Name         string
+	Replications int
+	Mean         float64
+	StdDev       float64
+	Eliminated   bool
+	EliminatedBy string
+}
+
+// Metamodel is a polynomial regression of one response on the numeric
+// factors of a sweep or design, with the factors coded to [-1, 1]
+type Metamodel struct {
+	Response          string
+	Degree            int
+	Factors           []MetamodelFactor
+	Terms             []*MetamodelTerm
+	Points            int
+	Freedom           int
+	RSquared          float64
+	AdjustedRSquared  float64
+	PredictedRSquared float64
+	ResidualStdError  float64
+	ConfidenceLevel   float64
+	Covariance        [][]float64
+	OutputFile        string
+}
+
+// MetamodelFactor is an input of a metamodel and the range it was fitted on
+type MetamodelFactor struct {
+	Field string
+	Low   float64
+	High  float64
+}
+
+// MetamodelTerm is a product of coded factors raised to the given powers
+type MetamodelTerm struct {
+	Label       string
+	Powers      []int
+	Coefficient float64
+	StdError    float64
+}
+
+// MetamodelPrediction is the metamodel's estimate at one setting
+type MetamodelPrediction struct {
+	Response      string
+	Settings      []SweepSetting
+	Mean          float64
+	StdError      float64
+	Confidence    [2]float64
+	HasConfidence bool
+	Extrapolated  []string
+}
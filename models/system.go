@@ -4,52 +4,234 @@ import "time"
 
 // ComprehensiveMetrics holds ALL performance metrics
 type ComprehensiveMetrics struct {
-	AverageWaitTime        float64
-	AverageQueueLength     float64
-	ServerUtilization      float64
-	AverageSystemTime      float64
-	AverageInSystem        float64
-	Throughput             float64
-	QueueProbability       float64
-	RejectedCustomers      int
-	TotalCustomers         int
-	BlockingProbability    float64
-	WaitTimeVariance       float64
-	SystemTimeVariance     float64
-	MaxQueueLength         int
-	MaxWaitTime            float64
-	ServerIdleTime         float64
-	ServerBusyTime         float64
-	WaitTimeConfidence     [2]float64
-	SystemTimeConfidence   [2]float64
-	WaitTimePercentiles    map[string]float64
-	SystemTimePercentiles  map[string]float64
+	AverageWaitTime          float64
+	AverageQueueLength       float64
+	ServerUtilization        float64
+	AverageSystemTime        float64
+	AverageInSystem          float64
+	Throughput               float64
+	QueueProbability         float64
+	RejectedCustomers        int
+	TotalCustomers           int
+	BlockingProbability      float64
+	WaitTimeVariance         float64
+	SystemTimeVariance       float64
+	MaxQueueLength           int
+	MaxWaitTime              float64
+	ServerIdleTime           float64
+	ServerBusyTime           float64
+	WaitTimeConfidence       [2]float64
+	SystemTimeConfidence     [2]float64
+	WaitTimePercentiles      map[string]float64
+	SystemTimePercentiles    map[string]float64
+	QueueLengthPercentiles   map[string]float64
+	WaitTimeQuantiles        []*QuantileEstimate
+	SystemTimeQuantiles      []*QuantileEstimate
+	QueueLengthQuantiles     []*QuantileEstimate
+	SystemSizeDistribution   []float64
+	QueueLengthDistribution  []float64
+	ConsistencyChecks        []*ConsistencyCheck
+	ConsistencyNote          string
+	BusyPeriods              *BusyPeriodAnalysis
+	DepartureProcess         *DepartureProcess
+	SLA                      []*SLAResult
+	Cost                     *CostAnalysis
+	WaitTimeControlVariate   *ControlVariateEstimate
+	SystemTimeControlVariate *ControlVariateEstimate
+	WarmupTime               float64
+	WarmupCustomers          int
+	ConfidenceLevel          float64
+	WaitTimeBatchMeans       *BatchMeansResult
+	SystemTimeBatchMeans     *BatchMeansResult
+	Regenerative             *RegenerativeAnalysis
+}
+
+// DepartureProcess describes the inter-departure times of the output process
+type DepartureProcess struct {
+	Count                  int
+	Rate                   float64
+	MeanInterDeparture     float64
+	InterDepartureVariance float64
+	SCV                    float64
+	Autocorrelations       []float64
+	ExportFile             string
+}
+
+// CostAnalysis totals the cost components over the observation period
+type CostAnalysis struct {
+	Waiting               float64
+	Holding               float64
+	Staffing              float64
+	Service               float64
+	Rejection             float64
+	Revenue               float64
+	Total                 float64
+	Rate                  float64
+	RateConfidence        [2]float64
+	PerCustomer           float64
+	PerCustomerConfidence [2]float64
+}
+
+// SLAResult is the attained fraction of one service-level target
+type SLAResult struct {
+	Name       string
+	Metric     string
+	Type       string
+	Threshold  float64
+	Target     float64
+	Attained   float64
+	Confidence [2]float64
+	Passed     bool
+	Conclusive bool
+}
+
+// BusyPeriodAnalysis describes the busy and idle periods of the servers
+type BusyPeriodAnalysis struct {
+	Busy                   *PeriodStatistics
+	Idle                   *PeriodStatistics
+	CustomersPerBusyPeriod *PeriodStatistics
+}
+
+// PeriodStatistics summarizes a set of complete periods
+type PeriodStatistics struct {
+	Count      int
+	Mean       float64
+	Variance   float64
+	StdDev     float64
+	Min        float64
+	Max        float64
+	Confidence [2]float64
+	Histogram  []HistogramBin
+}
+
+// ConsistencyCheck compares two sides of a conservation law after a run
+type ConsistencyCheck struct {
+	Name          string
+	Expected      float64
+	Observed      float64
+	RelativeError float64
+	Tolerance     float64
+	Passed        bool
+	Skipped       bool
+}
+
+// QuantileEstimate is a quantile with its confidence interval
+type QuantileEstimate struct {
+	Level      float64
+	Key        string
+	Estimate   float64
+	Confidence [2]float64
+}
+
+// RegenerativeAnalysis holds ratio estimates built from regeneration cycles
+type RegenerativeAnalysis struct {
+	Cycles                 int
+	CycleLengthMean        float64
+	CycleLengthStdDev      float64
+	CycleLengthMin         float64
+	CycleLengthMax         float64
+	CycleLengthPercentiles map[string]float64
+	CycleLengthHistogram   []HistogramBin
+	CustomersPerCycle      float64
+	Estimates              []*RatioEstimate
+}
+
+// RatioEstimate is a regenerative ratio estimator with its CI
+type RatioEstimate struct {
+	Metric     string
+	Estimate   float64
+	Confidence [2]float64
+}
+
+// HistogramBin counts observations in [Lower, Upper)
+type HistogramBin struct {
+	Lower float64
+	Upper float64
+	Count int
+}
+
+// BatchMeansResult describes how a batch-means confidence interval was built
+type BatchMeansResult struct {
+	Method              string
+	Batches             int
+	BatchSize           int
+	DegreesOfFreedom    float64
+	HalfWidth           float64
+	Lag1Autocorrelation float64
+}
+
+// ControlVariateEstimate holds a control-variate adjusted mean and its CI
+type ControlVariateEstimate struct {
+	Estimate          float64
+	Confidence        [2]float64
+	Controls          []string
+	Coefficients      []float64
+	Batches           int
+	VarianceReduction float64
 }
 
 // SimulationResults holds complete simulation results
 type SimulationResults struct {
-	Config      *SimulationConfig
-	Metrics     *ComprehensiveMetrics
-	State       *SystemState
-	Runtime     time.Duration
-	EventLog    []*EventLogEntry
+	Config     *SimulationConfig
+	Metrics    *ComprehensiveMetrics
+	State      *SystemState
+	Runtime    time.Duration
+	EventLog   []*EventLogEntry
+	Warmup     *WarmupAnalysis
+	Precision  *PrecisionReport
+	StopReason string
+	Theory     *TheoryValidation
+	TimeSeries *TimeSeries
+	Warnings   []string
+}
+
+// TimeSeries holds metrics over sliding windows of simulated time
+type TimeSeries struct {
+	Window     float64
+	Step       float64
+	Points     []*TimeSeriesPoint
+	ExportFile string
+}
+
+// TimeSeriesPoint describes one window [Start, End)
+type TimeSeriesPoint struct {
+	Start       float64
+	End         float64
+	ArrivalRate float64
+	Throughput  float64
+	Utilization float64
+	QueueLength float64
+	InSystem    float64
+	MeanWait    float64
+	Blocking    float64
+}
+
+// WarmupAnalysis records an automatically detected truncation point
+type WarmupAnalysis struct {
+	Method              string
+	PilotReplications   int
+	TruncationCustomers int
+	Window              int
+	AveragedSeries      []float64
+	MovingAverage       []float64
 }
 
 // SystemState represents the current state of the simulation
 type SystemState struct {
-	Clock              float64
-	ServerBusy         bool
-	Queue              []*Customer
-	NextArrivalTime    float64
-	NextDepartureTime  float64
-	CustomersServed    int
-	TotalCustomers     int
-	TotalDelay         float64
-	AreaUnderQ         float64
-	AreaUnderB         float64
-	LastEventTime      float64
-	EventsProcessed    int
-	RejectedCustomers  int
+	Clock             float64
+	ServerBusy        bool
+	BusyServers       int
+	Queue             []*Customer
+	NextArrivalTime   float64
+	NextDepartureTime float64
+	CustomersServed   int
+	TotalCustomers    int
+	TotalDelay        float64
+	AreaUnderQ        float64
+	AreaUnderB        float64
+	LastEventTime     float64
+	EventsProcessed   int
+	RejectedCustomers int
 }
 
 // Event represents a discrete event in the simulation
@@ -0,0 +1,228 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+)
+
+// AnalyticReference returns the closed-form steady-state measures of the
+// queue described by config: M/M/1, M/M/1/K, M/M/c, M/M/c/K or M/G/1. It
+// returns an error when no closed form applies or the queue is unstable.
+func AnalyticReference(config *models.SimulationConfig) (*models.AnalyticModel, error) {
+	lambda, mu := config.ArrivalRate, config.ServiceRate
+	if lambda <= 0 || mu <= 0 {
+		return nil, fmt.Errorf("arrival and service rates must be positive")
+	}
+	if !isExponential(ArrivalDistribution(config)) {
+		return nil, fmt.Errorf("no closed form for %s interarrival times", ArrivalDistribution(config))
+	}
+	c := serverCount(config)
+	finite := config.MaxQueueSize >= 0
+
+	if isExponential(ServiceDistribution(config)) {
+		if finite {
+			return finiteMarkovian(lambda, mu, c, c+config.MaxQueueSize), nil
+		}
+		return erlangC(lambda, mu, c)
+	}
+	if c == 1 && !finite {
+		mean, secondMoment, err := serviceMoments(ServiceDistribution(config), mu)
+		if err != nil {
+			return nil, err
+		}
+		return pollaczekKhinchine(lambda, mean, secondMoment)
+	}
+	return nil, fmt.Errorf("no closed form for %s service with %d server(s) and a finite queue", ServiceDistribution(config), c)
+}
+
+func isExponential(distribution string) bool {
+	return distribution == "" || distribution == "exponential"
+}
+
+// serviceMoments returns E[S] and E[S^2] of the service distributions
+// GetServiceTime draws from.
+func serviceMoments(distribution string, mu float64) (float64, float64, error) {
+	mean := 1 / mu
+	switch distribution {
+	case "uniform":
+		// uniform on [0.5/mu, 1.5/mu]: variance (1/mu)^2 / 12
+		return mean, mean * mean * (1 + 1.0/12), nil
+	case "constant":
+		return mean, mean * mean, nil
+	}
+	return 0, 0, fmt.Errorf("unknown service distribution %q", distribution)
+}
+
+// erlangC solves M/M/c with an unlimited queue using the Erlang C formula.
+func erlangC(lambda, mu float64, c int) (*models.AnalyticModel, error) {
+	a := lambda / mu
+	rho := a / float64(c)
+	if rho >= 1 {
+		return nil, fmt.Errorf("unstable queue: utilization %.4f >= 1", rho)
+	}
+
+	term, sum := 1.0, 0.0
+	for n := 0; n < c; n++ {
+		sum += term
+		term *= a / float64(n+1)
+	}
+	// term is now a^c / c!
+	tail := term / (1 - rho)
+	waitProbability := tail / (sum + tail)
+
+	lq := waitProbability * rho / (1 - rho)
+	wq := lq / lambda
+	w := wq + 1/mu
+	name := "M/M/1"
+	if c > 1 {
+		name = fmt.Sprintf("M/M/%d", c)
+	}
+	return &models.AnalyticModel{
+		Name:        name,
+		L:           lambda * w,
+		Lq:          lq,
+		W:           w,
+		Wq:          wq,
+		Utilization: rho,
+		Throughput:  lambda,
+	}, nil
+}
+
+// finiteMarkovian solves M/M/c/K, K the system capacity, from the
+// birth-death balance equations p(n) = p(n-1) * a / min(n, c).
+func finiteMarkovian(lambda, mu float64, c, capacity int) *models.AnalyticModel {
+	a := lambda / mu
+	p := make([]float64, capacity+1)
+	p[0] = 1
+	total := 1.0
+	for n := 1; n <= capacity; n++ {
+		p[n] = p[n-1] * a / float64(min(n, c))
+		total += p[n]
+	}
+
+	l, lq := 0.0, 0.0
+	for n := range p {
+		p[n] /= total
+		l += float64(n) * p[n]
+		if n > c {
+			lq += float64(n-c) * p[n]
+		}
+	}
+	blocking := p[capacity]
+	throughput := lambda * (1 - blocking)
+	return &models.AnalyticModel{
+		Name:        fmt.Sprintf("M/M/%d/%d", c, capacity),
+		L:           l,
+		Lq:          lq,
+		W:           l / throughput,
+		Wq:          lq / throughput,
+		Utilization: throughput / (mu * float64(c)),
+		Blocking:    blocking,
+		Throughput:  throughput,
+	}
+}
+
+// pollaczekKhinchine solves M/G/1 from the first two service moments.
+func pollaczekKhinchine(lambda, mean, secondMoment float64) (*models.AnalyticModel, error) {
+	rho := lambda * mean
+	if rho >= 1 {
+		return nil, fmt.Errorf("unstable queue: utilization %.4f >= 1", rho)
+	}
+	lq := lambda * lambda * secondMoment / (2 * (1 - rho))
+	wq := lq / lambda
+	w := wq + mean
+	return &models.AnalyticModel{
+		Name:        "M/G/1",
+		L:           lambda * w,
+		Lq:          lq,
+		W:           w,
+		Wq:          wq,
+		Utilization: rho,
+		Throughput:  lambda,
+	}, nil
+}
+
+// theoryMetrics pairs the analytic measures with the metric names they
+// validate.
+var theoryMetrics = []struct {
+	name  string
+	value func(m *models.AnalyticModel) float64
+}{
+	{"average_in_system", func(m *models.AnalyticModel) float64 { return m.L }},
+	{"average_queue_length", func(m *models.AnalyticModel) float64 { return m.Lq }},
+	{"average_system_time", func(m *models.AnalyticModel) float64 { return m.W }},
+	{"average_wait_time", func(m *models.AnalyticModel) float64 { return m.Wq }},
+	{"server_utilization", func(m *models.AnalyticModel) float64 { return m.Utilization }},
+	{"blocking_probability", func(m *models.AnalyticModel) float64 { return m.Blocking }},
+	{"throughput", func(m *models.AnalyticModel) float64 { return m.Throughput }},
+}
+
+// simulatedValue returns a metric's simulated value and, if one exists, its
+// confidence interval.
+type simulatedValue func(name string) (float64, [2]float64, bool)
+
+// CompareWithTheory validates a single run. Wait and system time use their
+// batch-means intervals; the other metrics use the regenerative ratio
+// estimate and its interval when the regenerative analysis is enabled, and
+// have no interval otherwise.
+func CompareWithTheory(config *models.SimulationConfig, metrics *models.ComprehensiveMetrics) *models.TheoryValidation {
+	return compareWithTheory(config, func(name string) (float64, [2]float64, bool) {
+		def, _ := LookupMetric(config, name)
+		value := def.Value(metrics)
+		switch name {
+		case "average_wait_time":
+			return value, metrics.WaitTimeConfidence, !math.IsNaN(metrics.WaitTimeConfidence[0])
+		case "average_system_time":
+			return value, metrics.SystemTimeConfidence, !math.IsNaN(metrics.SystemTimeConfidence[0])
+		}
+		if metrics.Regenerative != nil {
+			for _, estimate := range metrics.Regenerative.Estimates {
+				if estimate.Metric == name {
+					return estimate.Estimate, estimate.Confidence, true
+				}
+			}
+		}
+		return value, [2]float64{}, false
+	})
+}
+
+// CompareReplicationsWithTheory validates the replication means against
+// their Student-t intervals.
+func CompareReplicationsWithTheory(config *models.SimulationConfig, summary *models.ReplicationSummary) *models.TheoryValidation {
+	return compareWithTheory(config, func(name string) (float64, [2]float64, bool) {
+		metric := FindMetric(summary, name)
+		if metric == nil {
+			return math.NaN(), [2]float64{}, false
+		}
+		return metric.Mean, metric.Confidence, len(metric.Values) > 1
+	})
+}
+
+func compareWithTheory(config *models.SimulationConfig, simulated simulatedValue) *models.TheoryValidation {
+	validation := &models.TheoryValidation{ConfidenceLevel: confidenceLevel(config)}
+	model, err := AnalyticReference(config)
+	if err != nil {
+		validation.Unavailable = err.Error()
+		return validation
+	}
+	validation.Model = model
+
+	for _, metric := range theoryMetrics {
+		def, _ := LookupMetric(config, metric.name)
+		comparison := &models.TheoryComparison{
+			Metric:        metric.name,
+			Label:         def.Label,
+			Analytic:      metric.value(model),
+			RelativeError: math.NaN(),
+		}
+		comparison.Simulated, comparison.Confidence, comparison.HasConfidence = simulated(metric.name)
+		if comparison.Analytic != 0 {
+			comparison.RelativeError = (comparison.Simulated - comparison.Analytic) / comparison.Analytic
+		}
+		comparison.WithinCI = comparison.HasConfidence &&
+			comparison.Confidence[0] <= comparison.Analytic && comparison.Analytic <= comparison.Confidence[1]
+		validation.Comparisons = append(validation.Comparisons, comparison)
+	}
+	return validation
+}
@@ -0,0 +1,96 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-6*math.Max(1, math.Abs(want))
+}
+
+func checkModel(t *testing.T, got, want *models.AnalyticModel) {
+	t.Helper()
+	if got.Name != want.Name {
+		t.Errorf("name %q, want %q", got.Name, want.Name)
+	}
+	fields := []struct {
+		name      string
+		got, want float64
+	}{
+		{"L", got.L, want.L},
+		{"Lq", got.Lq, want.Lq},
+		{"W", got.W, want.W},
+		{"Wq", got.Wq, want.Wq},
+		{"utilization", got.Utilization, want.Utilization},
+		{"blocking", got.Blocking, want.Blocking},
+		{"throughput", got.Throughput, want.Throughput},
+	}
+	for _, f := range fields {
+		if !closeTo(f.got, f.want) {
+			t.Errorf("%s: %s = %.6f, want %.6f", want.Name, f.name, f.got, f.want)
+		}
+	}
+}
+
+func TestErlangC(t *testing.T) {
+	tests := []struct {
+		lambda, mu float64
+		c          int
+		want       models.AnalyticModel
+	}{
+		// rho = 1/2: L = rho/(1-rho), W = 1/(mu-lambda)
+		{1, 2, 1, models.AnalyticModel{Name: "M/M/1", L: 1, Lq: 0.5, W: 1, Wq: 0.5, Utilization: 0.5, Throughput: 1}},
+		// a = 4/3, rho = 2/3: P(wait) = (8/9 * 3) / (1 + 4/3 + 8/3) = 8/15
+		{2, 1.5, 2, models.AnalyticModel{Name: "M/M/2", L: 2.4, Lq: 16.0 / 15, W: 1.2, Wq: 8.0 / 15, Utilization: 2.0 / 3, Throughput: 2}},
+		// a = 2, rho = 2/3: P(wait) = (4/3 * 3) / (1 + 2 + 2 + 4) = 4/9
+		{2, 1, 3, models.AnalyticModel{Name: "M/M/3", L: 2 + 8.0/9, Lq: 8.0 / 9, W: 1 + 4.0/9, Wq: 4.0 / 9, Utilization: 2.0 / 3, Throughput: 2}},
+	}
+	for _, tt := range tests {
+		got, err := erlangC(tt.lambda, tt.mu, tt.c)
+		if err != nil {
+			t.Fatalf("erlangC(%g, %g, %d): %v", tt.lambda, tt.mu, tt.c, err)
+		}
+		checkModel(t, got, &tt.want)
+	}
+
+	for _, c := range []int{1, 2} {
+		if _, err := erlangC(2*float64(c), 2, c); err == nil {
+			t.Errorf("erlangC with utilization 1 and %d servers: expected an error", c)
+		}
+	}
+}
+
+func TestFiniteMarkovian(t *testing.T) {
+	tests := []struct {
+		lambda, mu  float64
+		c, capacity int
+		want        models.AnalyticModel
+	}{
+		// a = 1: every state has probability 1/4
+		{1, 1, 1, 3, models.AnalyticModel{Name: "M/M/1/3", L: 1.5, Lq: 0.75, W: 2, Wq: 1, Utilization: 0.75, Blocking: 0.25, Throughput: 0.75}},
+		// a = 1/2: p = 4/7, 2/7, 1/7
+		{1, 2, 1, 2, models.AnalyticModel{Name: "M/M/1/2", L: 4.0 / 7, Lq: 1.0 / 7, W: 2.0 / 3, Wq: 1.0 / 6, Utilization: 3.0 / 7, Blocking: 1.0 / 7, Throughput: 6.0 / 7}},
+		// Erlang loss, a = 2: p = 1/5, 2/5, 2/5
+		{2, 1, 2, 2, models.AnalyticModel{Name: "M/M/2/2", L: 1.2, W: 1, Utilization: 0.6, Blocking: 0.4, Throughput: 1.2}},
+	}
+	for _, tt := range tests {
+		checkModel(t, finiteMarkovian(tt.lambda, tt.mu, tt.c, tt.capacity), &tt.want)
+	}
+}
+
+func TestFiniteMarkovianApproachesErlangC(t *testing.T) {
+	for _, c := range []int{1, 2, 4} {
+		lambda := 0.7 * float64(c)
+		unlimited, err := erlangC(lambda, 1, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		finite := finiteMarkovian(lambda, 1, c, 500)
+		if !closeTo(finite.L, unlimited.L) || !closeTo(finite.Wq, unlimited.Wq) || finite.Blocking > 1e-12 {
+			t.Errorf("M/M/%d/500: L %.6f, Wq %.6f, blocking %g; M/M/%d: L %.6f, Wq %.6f",
+				c, finite.L, finite.Wq, finite.Blocking, c, unlimited.L, unlimited.Wq)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+)
+
+const (
+	methodIID                   = "iid"
+	methodBatchMeans            = "batch_means"
+	methodOverlappingBatchMeans = "overlapping_batch_means"
+
+	maxAutoBatches     = 512
+	minBatches         = 10
+	autocorrelationCap = 0.2
+)
+
+// batchMeansInterval estimates the half-width of a confidence interval for
+// the mean of an autocorrelated series. With batches <= 0 the batch size is
+// doubled, starting from at most maxAutoBatches batches, until the lag-1
+// autocorrelation of the batch means drops below autocorrelationCap or
+// another doubling would leave fewer than minBatches batches. It returns nil
+// when the series is too short.
+func batchMeansInterval(series []float64, method string, batches int, level float64) *models.BatchMeansResult {
+	n := len(series)
+	if n < 2*minBatches {
+		return nil
+	}
+
+	var means []float64
+	if batches > 0 {
+		if batches > n/2 {
+			batches = n / 2
+		}
+		means = batchMeans(series, n/batches)
+	} else {
+		batchSize := 1
+		if n > maxAutoBatches {
+			batchSize = n / maxAutoBatches
+		}
+		means = batchMeans(series, batchSize)
+		for len(means)/2 >= minBatches && lagAutocorrelation(means, 1) > autocorrelationCap {
+			batchSize *= 2
+			means = batchMeans(series, batchSize)
+		}
+	}
+	if len(means) < 2 {
+		return nil
+	}
+
+	b := len(means)
+	m := n / b
+	result := &models.BatchMeansResult{
+		Method:              methodBatchMeans,
+		Batches:             b,
+		BatchSize:           m,
+		DegreesOfFreedom:    float64(b - 1),
+		Lag1Autocorrelation: lagAutocorrelation(means, 1),
+	}
+	variance := sampleVariance(means) / float64(b)
+
+	if method == methodOverlappingBatchMeans && m > 1 {
+		result.Method = methodOverlappingBatchMeans
+		result.DegreesOfFreedom = 1.5 * float64(b-1)
+		variance = overlappingBatchMeansVariance(series[:b*m], m) / float64(b*m)
+	}
+
+	result.HalfWidth = StudentTQuantile(1-(1-level)/2, result.DegreesOfFreedom) * math.Sqrt(variance)
+	return result
+}
+
+// batchMeans returns the means of consecutive non-overlapping batches of the
+// given size; a trailing partial batch is dropped.
+func batchMeans(series []float64, batchSize int) []float64 {
+	b := len(series) / batchSize
+	means := make([]float64, b)
+	for i := 0; i < b; i++ {
+		means[i] = sampleMean(series[i*batchSize : (i+1)*batchSize])
+	}
+	return means
+}
+
+// overlappingBatchMeansVariance returns the OBM estimator of the variance
+// parameter, n*m / ((n-m+1)(n-m)) * sum over all n-m+1 windows of
+// (window mean - grand mean)^2.
+func overlappingBatchMeansVariance(series []float64, m int) float64 {
+	n := len(series)
+	grand := sampleMean(series)
+	window := 0.0
+	for i := 0; i < m; i++ {
+		window += series[i]
+	}
+	sumSq := 0.0
+	for start := 0; start+m <= n; start++ {
+		if start > 0 {
+			window += series[start+m-1] - series[start-1]
+		}
+		d := window/float64(m) - grand
+		sumSq += d * d
+	}
+	return float64(n) * float64(m) / (float64(n-m+1) * float64(n-m)) * sumSq
+}
+
+func lagAutocorrelation(values []float64, lag int) float64 {
+	if len(values) <= lag {
+		return 0
+	}
+	mean := sampleMean(values)
+	numerator, denominator := 0.0, 0.0
+	for i, v := range values {
+		denominator += (v - mean) * (v - mean)
+		if i+lag < len(values) {
+			numerator += (v - mean) * (values[i+lag] - mean)
+		}
+	}
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
@@ -0,0 +1,102 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// ar1 returns n values of x_i = phi x_{i-1} + e_i, e_i standard normal,
+// started from the stationary distribution, so the true mean is 0.
+func ar1(n int, phi float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	series := make([]float64, n)
+	x := rng.NormFloat64() / math.Sqrt(1-phi*phi)
+	for i := range series {
+		series[i] = x
+		x = phi*x + rng.NormFloat64()
+	}
+	return series
+}
+
+func TestBatchMeansIntervalFixedBatches(t *testing.T) {
+	series := ar1(1000, 0.9, 1)
+	result := batchMeansInterval(series, methodBatchMeans, 10, 0.95)
+	if result == nil {
+		t.Fatal("no interval")
+	}
+	if result.Batches != 10 || result.BatchSize != 100 || result.DegreesOfFreedom != 9 {
+		t.Fatalf("%d batches of %d with df %g, want 10 of 100 with df 9", result.Batches, result.BatchSize, result.DegreesOfFreedom)
+	}
+	means := make([]float64, 10)
+	for i := range means {
+		means[i] = sampleMean(series[i*100 : (i+1)*100])
+	}
+	// t_{0.975, 9} = 2.262157
+	want := 2.262157 * math.Sqrt(sampleVariance(means)/10)
+	if math.Abs(result.HalfWidth-want) > 1e-5*want {
+		t.Errorf("half-width %.6f, want %.6f", result.HalfWidth, want)
+	}
+	if math.Abs(result.Lag1Autocorrelation-lagAutocorrelation(means, 1)) > 1e-12 {
+		t.Errorf("lag-1 autocorrelation %g, want %g", result.Lag1Autocorrelation, lagAutocorrelation(means, 1))
+	}
+}
+
+func TestBatchMeansIntervalBatchCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		phi     float64
+		batches int
+	}{
+		// independent values keep the maxAutoBatches starting batches
+		{"independent", 5120, 0, maxAutoBatches},
+		// doubling from 512 batches of 1 stops at 10 rather than 5
+		{"strongly correlated, short", 640, 0.999, 10},
+		{"halving would leave 7", 30, 0.999, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := batchMeansInterval(ar1(tt.n, tt.phi, 2), methodBatchMeans, 0, 0.95)
+			if result == nil {
+				t.Fatal("no interval")
+			}
+			if result.Batches != tt.batches {
+				t.Errorf("%d batches, want %d", result.Batches, tt.batches)
+			}
+		})
+	}
+
+	// otherwise doubling stops once the batch means are nearly uncorrelated
+	result := batchMeansInterval(ar1(100000, 0.9, 3), methodBatchMeans, 0, 0.95)
+	if result.Lag1Autocorrelation > autocorrelationCap || result.Batches < minBatches {
+		t.Errorf("%d batches with lag-1 autocorrelation %.3f", result.Batches, result.Lag1Autocorrelation)
+	}
+	if batchMeansInterval(ar1(2*minBatches-1, 0.5, 4), methodBatchMeans, 0, 0.95) != nil {
+		t.Error("interval from fewer than 2*minBatches values")
+	}
+}
+
+func TestBatchMeansIntervalCoverage(t *testing.T) {
+	// for AR(1) with phi = 0.9 the iid interval is about sqrt(19) times too
+	// narrow; batch means should cover the true mean close to 95% of the time
+	const experiments = 400
+	covered, iidCovered := 0, 0
+	for e := 0; e < experiments; e++ {
+		series := ar1(4000, 0.9, int64(100+e))
+		mean := sampleMean(series)
+		if result := batchMeansInterval(series, methodBatchMeans, 0, 0.95); math.Abs(mean) <= result.HalfWidth {
+			covered++
+		}
+		iid := TCritical(0.95, len(series)-1) * math.Sqrt(sampleVariance(series)/float64(len(series)))
+		if math.Abs(mean) <= iid {
+			iidCovered++
+		}
+	}
+	if got := float64(covered) / experiments; got < 0.9 {
+		t.Errorf("batch-means coverage %.3f, want about 0.95", got)
+	}
+	if got := float64(iidCovered) / experiments; got > 0.5 {
+		t.Errorf("iid coverage %.3f, expected far below 0.95", got)
+	}
+}
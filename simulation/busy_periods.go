@@ -0,0 +1,105 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+)
+
+const busyPeriodHistogramBins = 10
+
+// periodAccumulator collects the lengths of one kind of period. Values are
+// kept for the histogram unless streaming is enabled, in which case only the
+// moments and extremes are tracked.
+type periodAccumulator struct {
+	moments welford
+	min     float64
+	max     float64
+	values  []float64
+	store   bool
+}
+
+func newPeriodAccumulator(store bool) *periodAccumulator {
+	return &periodAccumulator{min: math.Inf(1), max: math.Inf(-1), store: store}
+}
+
+func (pa *periodAccumulator) add(x float64) {
+	pa.moments.add(x)
+	pa.min = math.Min(pa.min, x)
+	pa.max = math.Max(pa.max, x)
+	if pa.store {
+		pa.values = append(pa.values, x)
+	}
+}
+
+// statistics summarizes the periods with a Student-t interval for the mean.
+// Busy periods, and idle periods under Poisson arrivals, start at
+// regeneration points, so consecutive periods are independent.
+func (pa *periodAccumulator) statistics(level float64) *models.PeriodStatistics {
+	n := pa.moments.n
+	if n == 0 {
+		return nil
+	}
+	variance := 0.0
+	if n > 1 {
+		variance = pa.moments.m2 / float64(n-1)
+	}
+	stats := &models.PeriodStatistics{
+		Count:      n,
+		Mean:       pa.moments.mean,
+		Variance:   variance,
+		StdDev:     math.Sqrt(variance),
+		Min:        pa.min,
+		Max:        pa.max,
+		Confidence: [2]float64{pa.moments.mean, pa.moments.mean},
+		Histogram:  buildHistogram(pa.values, busyPeriodHistogramBins),
+	}
+	if n > 1 {
+		halfWidth := TCritical(level, n-1) * math.Sqrt(variance/float64(n))
+		stats.Confidence = [2]float64{stats.Mean - halfWidth, stats.Mean + halfWidth}
+	}
+	return stats
+}
+
+// resetPeriods discards all periods, including the ones in progress, whose
+// start was not observed.
+func (sc *EnhancedStatisticsCollector) resetPeriods(idle bool, t float64) {
+	store := !sc.config.Streaming.Enabled
+	sc.busyPeriods = newPeriodAccumulator(store)
+	sc.idlePeriods = newPeriodAccumulator(store)
+	sc.busyCustomers = newPeriodAccumulator(store)
+	sc.busyOpen = false
+	sc.idleOpen = idle
+	sc.idleStart = t
+}
+
+// StartBusyPeriod is called when an arrival finds every server idle. It
+// closes the idle period in progress.
+func (sc *EnhancedStatisticsCollector) StartBusyPeriod(t float64) {
+	if sc.idleOpen {
+		sc.idlePeriods.add(t - sc.idleStart)
+	}
+	sc.idleOpen = false
+	sc.busyOpen = true
+	sc.busyStart = t
+	sc.busyServed = 0
+}
+
+// EndBusyPeriod is called when the last busy server becomes idle.
+func (sc *EnhancedStatisticsCollector) EndBusyPeriod(t float64) {
+	if sc.busyOpen {
+		sc.busyPeriods.add(t - sc.busyStart)
+		sc.busyCustomers.add(float64(sc.busyServed))
+	}
+	sc.busyOpen = false
+	sc.idleOpen = true
+	sc.idleStart = t
+}
+
+func (sc *EnhancedStatisticsCollector) calculateBusyPeriods() {
+	level := confidenceLevel(sc.config)
+	sc.metrics.BusyPeriods = &models.BusyPeriodAnalysis{
+		Busy:                   sc.busyPeriods.statistics(level),
+		Idle:                   sc.idlePeriods.statistics(level),
+		CustomersPerBusyPeriod: sc.busyCustomers.statistics(level),
+	}
+}
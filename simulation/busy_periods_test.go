@@ -0,0 +1,63 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func TestBusyPeriodsOfTrace(t *testing.T) {
+	// service takes 2: busy on [1, 5] (2 customers), [6, 8] (1) and
+	// [9, 13] (2), idle on [0, 1], [5, 6] and [8, 9]
+	config := &models.SimulationConfig{
+		SimulationTime: 100,
+		ServiceRate:    0.5,
+		MaxQueueSize:   -1,
+		StopCondition:  models.StopCondition{AutomaticMode: true, Type: stopTime},
+		Random: models.RandomConfig{
+			Seed:                1,
+			ArrivalDistribution: "trace",
+			ServiceDistribution: "constant",
+			ArrivalTimes:        []float64{1, 2, 6, 9, 10.5},
+		},
+	}
+	analysis := newSilentSimulator(config, 1, false).Run().Metrics.BusyPeriods
+
+	// busy lengths 4, 2, 4: mean 10/3, s^2 = 4/3, t_{0.975,2} = 4.302653
+	busy := analysis.Busy
+	halfWidth := 4.302653 * math.Sqrt(4.0/3/3)
+	if busy.Count != 3 || math.Abs(busy.Mean-10.0/3) > 1e-12 || math.Abs(busy.Variance-4.0/3) > 1e-12 ||
+		busy.Min != 2 || busy.Max != 4 {
+		t.Errorf("busy periods: %d with mean %g, variance %g, range [%g, %g]; want 3, 10/3, 4/3, [2, 4]",
+			busy.Count, busy.Mean, busy.Variance, busy.Min, busy.Max)
+	}
+	if math.Abs(busy.Confidence[0]-(10.0/3-halfWidth)) > 1e-5 || math.Abs(busy.Confidence[1]-(10.0/3+halfWidth)) > 1e-5 {
+		t.Errorf("busy period interval %v, want 10/3 +- %g", busy.Confidence, halfWidth)
+	}
+	if customers := analysis.CustomersPerBusyPeriod; customers.Count != 3 || math.Abs(customers.Mean-5.0/3) > 1e-12 {
+		t.Errorf("%d busy periods serve %g customers on average, want 3 serving 5/3", customers.Count, customers.Mean)
+	}
+	if idle := analysis.Idle; idle.Count != 3 || idle.Mean != 1 || idle.Variance != 0 || idle.Confidence != [2]float64{1, 1} {
+		t.Errorf("idle periods: %d with mean %g, variance %g, interval %v; want 3 of length 1",
+			idle.Count, idle.Mean, idle.Variance, idle.Confidence)
+	}
+}
+
+func TestBusyPeriodsMatchMM1(t *testing.T) {
+	// M/M/1 with lambda = 0.5, mu = 1: mean busy period 1/(mu-lambda) = 2,
+	// mean idle period 1/lambda = 2, customers per busy period 1/(1-rho) = 2
+	config := mm1TestConfig(0.5, 1, 20000)
+	analysis := newSilentSimulator(config, config.Random.Seed, false).Run().Metrics.BusyPeriods
+	for _, period := range []struct {
+		name  string
+		stats *models.PeriodStatistics
+	}{
+		{"busy period", analysis.Busy},
+		{"idle period", analysis.Idle},
+		{"customers per busy period", analysis.CustomersPerBusyPeriod},
+	} {
+		if period.stats.Confidence[0] > 2 || period.stats.Confidence[1] < 2 {
+			t.Errorf("%s interval [%.4f, %.4f] misses 2", period.name, period.stats.Confidence[0], period.stats.Confidence[1])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+)
+
+const defaultControlVariateBatches = 30
+
+type controlVariate struct {
+	name      string
+	knownMean float64
+	batches   []float64
+}
+
+// calculateControlVariates groups arrivals into consecutive batches and
+// regresses the batch mean of wait and system time on the batch means of the
+// sampled service and interarrival times, whose true means are known from the
+// configuration. Batching keeps the observations nearly independent, and
+// controlling over all arrivals (not only admitted ones) keeps their means
+// unbiased when the queue blocks.
+func (sc *EnhancedStatisticsCollector) calculateControlVariates() {
+	sc.metrics.WaitTimeControlVariate = nil
+	sc.metrics.SystemTimeControlVariate = nil
+	if !sc.config.ControlVariates.Enabled || len(sc.arrivalServiceTimes) == 0 {
+		return
+	}
+
+	batches := sc.config.ControlVariates.Batches
+	if batches <= 0 {
+		batches = defaultControlVariateBatches
+	}
+	batchSize := len(sc.arrivalServiceTimes) / batches
+	if batchSize < 1 {
+		return
+	}
+
+	waitSums := make([]float64, batches)
+	systemSums := make([]float64, batches)
+	counts := make([]int, batches)
+	for _, stats := range sc.customerStats {
+		batch := (stats.CustomerID - sc.firstArrivalID) / batchSize
+		if stats.CustomerID < sc.firstArrivalID || batch >= batches {
+			continue
+		}
+		waitSums[batch] += stats.WaitTime
+		systemSums[batch] += stats.SystemTime
+		counts[batch]++
+	}
+
+	controls := []*controlVariate{
+		{name: "service_time", knownMean: 1.0 / sc.config.ServiceRate},
+		{name: "interarrival_time", knownMean: 1.0 / sc.config.ArrivalRate},
+	}
+	var waitMeans, systemMeans []float64
+	for b := 0; b < batches; b++ {
+		if counts[b] == 0 {
+			continue
+		}
+		waitMeans = append(waitMeans, waitSums[b]/float64(counts[b]))
+		systemMeans = append(systemMeans, systemSums[b]/float64(counts[b]))
+		start, end := b*batchSize, (b+1)*batchSize
+		controls[0].batches = append(controls[0].batches, sampleMean(sc.arrivalServiceTimes[start:end]))
+		controls[1].batches = append(controls[1].batches, sampleMean(sc.arrivalInterarrivals[start:end]))
+	}
+
+	var usable []*controlVariate
+	for _, control := range controls {
+		// a replayed arrival trace has no known interarrival mean
+		if control.name == "interarrival_time" && ArrivalDistribution(sc.config) == "trace" {
+			continue
+		}
+		if sampleVariance(control.batches) > 1e-12 {
+			usable = append(usable, control)
+		}
+	}
+
+	level := confidenceLevel(sc.config)
+	sc.metrics.WaitTimeControlVariate = controlVariateEstimate(waitMeans, usable, level)
+	sc.metrics.SystemTimeControlVariate = controlVariateEstimate(systemMeans, usable, level)
+}
+
+// controlVariateEstimate computes the multiple-control estimator
+// Y - beta'(X - mu) with the Lavenberg-Welch variance estimate
+// s^2 * (1/n + d' Sxx^-1 d) and a t interval on n-q-1 degrees of freedom.
+func controlVariateEstimate(y []float64, controls []*controlVariate, level float64) *models.ControlVariateEstimate {
+	n := len(y)
+	q := len(controls)
+	if n < q+3 {
+		return nil
+	}
+
+	yMean := sampleMean(y)
+	xMeans := make([]float64, q)
+	deviations := make([]float64, q)
+	for j, control := range controls {
+		xMeans[j] = sampleMean(control.batches)
+		deviations[j] = xMeans[j] - control.knownMean
+	}
+
+	sxx := make([][]float64, q)
+	sxy := make([]float64, q)
+	for j := 0; j < q; j++ {
+		sxx[j] = make([]float64, q)
+		for i := 0; i < n; i++ {
+			dx := controls[j].batches[i] - xMeans[j]
+			sxy[j] += dx * (y[i] - yMean)
+			for k := 0; k < q; k++ {
+				sxx[j][k] += dx * (controls[k].batches[i] - xMeans[k])
+			}
+		}
+	}
+	syy := 0.0
+	for i := 0; i < n; i++ {
+		syy += (y[i] - yMean) * (y[i] - yMean)
+	}
+
+	beta := []float64{}
+	if q > 0 {
+		var ok bool
+		beta, ok = solveLinearSystem(sxx, sxy)
+		if !ok {
+			return nil
+		}
+	}
+
+	estimate := yMean
+	residualSS := syy
+	for j := 0; j < q; j++ {
+		estimate -= beta[j] * deviations[j]
+		residualSS -= beta[j] * sxy[j]
+	}
+	residualSS = math.Max(residualSS, 0)
+
+	inflation := 1.0 / float64(n)
+	if q > 0 {
+		sxxInvD, ok := solveLinearSystem(sxx, deviations)
+		if !ok {
+			return nil
+		}
+		for j := 0; j < q; j++ {
+			inflation += deviations[j] * sxxInvD[j]
+		}
+	}
+
+	df := n - q - 1
+	variance := residualSS / float64(df) * inflation
+	halfWidth := TCritical(level, df) * math.Sqrt(variance)
+
+	names := make([]string, q)
+	for j, control := range controls {
+		names[j] = control.name
+	}
+	return &models.ControlVariateEstimate{
+		Estimate:          estimate,
+		Confidence:        [2]float64{estimate - halfWidth, estimate + halfWidth},
+		Controls:          names,
+		Coefficients:      beta,
+		Batches:           n,
+		VarianceReduction: varianceReduction(variance, sampleVariance(y)/float64(n)),
+	}
+}
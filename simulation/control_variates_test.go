@@ -0,0 +1,79 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestControlVariateEstimate(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		name         string
+		y            []float64
+		controls     []*controlVariate
+		estimate     float64
+		coefficients []float64
+		halfWidth    float64
+		reduction    float64
+	}{
+		// x has mean 3, y mean 4: Sxx = 10, Sxy = 6, Syy = 6, beta = 0.6 and
+		// the residual sum of squares is 6 - 0.6*6 = 2.4 on 3 df
+		{
+			"control mean on target", []float64{2, 4, 5, 4, 5},
+			[]*controlVariate{{name: "x", knownMean: 3, batches: x}},
+			4, []float64{0.6},
+			3.182446 * math.Sqrt(2.4/3*(1.0/5)), 1 - 0.16/0.3,
+		},
+		// a deviation d = 0.5 shifts the estimate by -beta d and inflates
+		// the variance by d^2 / Sxx
+		{
+			"control mean off target", []float64{2, 4, 5, 4, 5},
+			[]*controlVariate{{name: "x", knownMean: 2.5, batches: x}},
+			4 - 0.6*0.5, []float64{0.6},
+			3.182446 * math.Sqrt(2.4/3*(1.0/5+0.25/10)), 1 - 0.18/0.3,
+		},
+		// y = 1 + 2 x1 - x2 exactly: the estimate is 1 + 2 mu1 - mu2 with
+		// no residual variance
+		{
+			"two exact controls", []float64{1 + 2*1 - 4, 1 + 2*2 - 1, 1 + 2*3 - 5, 1 + 2*4 - 2, 1 + 2*5 - 3, 1 + 2*6 - 6},
+			[]*controlVariate{
+				{name: "x1", knownMean: 3, batches: []float64{1, 2, 3, 4, 5, 6}},
+				{name: "x2", knownMean: 4, batches: []float64{4, 1, 5, 2, 3, 6}},
+			},
+			1 + 2*3 - 4, []float64{2, -1},
+			0, 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := controlVariateEstimate(tt.y, tt.controls, 0.95)
+			if result == nil {
+				t.Fatal("no estimate")
+			}
+			if math.Abs(result.Estimate-tt.estimate) > 1e-9 {
+				t.Errorf("estimate %.9f, want %.9f", result.Estimate, tt.estimate)
+			}
+			for j, want := range tt.coefficients {
+				if math.Abs(result.Coefficients[j]-want) > 1e-9 {
+					t.Errorf("coefficient %d: %.9f, want %.9f", j, result.Coefficients[j], want)
+				}
+			}
+			halfWidth := (result.Confidence[1] - result.Confidence[0]) / 2
+			if math.Abs(halfWidth-tt.halfWidth) > 1e-5 {
+				t.Errorf("half-width %.6f, want %.6f", halfWidth, tt.halfWidth)
+			}
+			if math.Abs(result.VarianceReduction-tt.reduction) > 1e-9 {
+				t.Errorf("variance reduction %.6f, want %.6f", result.VarianceReduction, tt.reduction)
+			}
+			if result.Batches != len(tt.y) {
+				t.Errorf("%d batches, want %d", result.Batches, len(tt.y))
+			}
+		})
+	}
+
+	// two controls need at least five batches for a positive df
+	short := []*controlVariate{{batches: []float64{1, 2, 3, 4}}, {batches: []float64{2, 1, 4, 3}}}
+	if result := controlVariateEstimate([]float64{1, 2, 3, 4}, short, 0.95); result != nil {
+		t.Errorf("estimate from 4 batches and 2 controls: %+v", result)
+	}
+}
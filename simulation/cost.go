@@ -0,0 +1,157 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+)
+
+// costEnabled reports whether any cost or revenue rate is configured.
+func costEnabled(config *models.SimulationConfig) bool {
+	c := config.Cost
+	return c.WaitingCost != 0 || c.HoldingCost != 0 || c.ServerCost != 0 ||
+		c.ServiceCost != 0 || c.RejectionPenalty != 0 || c.Revenue != 0
+}
+
+type costSection struct {
+	cost     float64
+	arrivals float64
+}
+
+// costAccumulator totals each cost component and spreads the net cost over
+// timeSections for the confidence intervals.
+type costAccumulator struct {
+	rates     models.CostConfig
+	servers   float64
+	waiting   float64
+	holding   float64
+	staffing  float64
+	service   float64
+	rejection float64
+	revenue   float64
+	arrivals  int
+	elapsed   float64
+	sections  *timeSections[*costSection]
+}
+
+func newCostAccumulator(config *models.SimulationConfig) *costAccumulator {
+	return &costAccumulator{
+		rates:   config.Cost,
+		servers: float64(serverCount(config)),
+		sections: newTimeSections(
+			func() *costSection { return &costSection{} },
+			func(into, from *costSection) {
+				into.cost += from.cost
+				into.arrivals += from.arrivals
+			},
+		),
+	}
+}
+
+// advance charges dt time units with the given queue length and busy servers.
+func (ca *costAccumulator) advance(queue, busy int, dt float64) {
+	waiting := ca.rates.WaitingCost * float64(queue) * dt
+	holding := ca.rates.HoldingCost * float64(queue+busy) * dt
+	staffing := ca.rates.ServerCost * ca.servers * dt
+	service := ca.rates.ServiceCost * float64(busy) * dt
+	ca.waiting += waiting
+	ca.holding += holding
+	ca.staffing += staffing
+	ca.service += service
+	ca.elapsed += dt
+
+	rate := (waiting + holding + staffing + service) / dt
+	ca.sections.advance(dt, func(section *costSection, step float64) {
+		section.cost += rate * step
+	})
+}
+
+func (ca *costAccumulator) arrival(rejected bool) {
+	section := ca.sections.current()
+	section.arrivals++
+	ca.arrivals++
+	if rejected {
+		ca.rejection += ca.rates.RejectionPenalty
+		section.cost += ca.rates.RejectionPenalty
+	}
+}
+
+func (ca *costAccumulator) completion() {
+	ca.revenue += ca.rates.Revenue
+	ca.sections.current().cost -= ca.rates.Revenue
+}
+
+// analysis returns the cost totals with a t interval for the cost rate over
+// the complete sections and a ratio-estimator interval for the cost per
+// arriving customer, both centred on the whole-run estimates.
+func (ca *costAccumulator) analysis(level float64) *models.CostAnalysis {
+	analysis := &models.CostAnalysis{
+		Waiting:     ca.waiting,
+		Holding:     ca.holding,
+		Staffing:    ca.staffing,
+		Service:     ca.service,
+		Rejection:   ca.rejection,
+		Revenue:     ca.revenue,
+		Total:       ca.waiting + ca.holding + ca.staffing + ca.service + ca.rejection - ca.revenue,
+		Rate:        math.NaN(),
+		PerCustomer: math.NaN(),
+	}
+	if ca.elapsed > 0 {
+		analysis.Rate = analysis.Total / ca.elapsed
+		analysis.RateConfidence = [2]float64{analysis.Rate, analysis.Rate}
+	}
+	if ca.arrivals > 0 {
+		analysis.PerCustomer = analysis.Total / float64(ca.arrivals)
+		analysis.PerCustomerConfidence = [2]float64{analysis.PerCustomer, analysis.PerCustomer}
+	}
+
+	sections := ca.sections.complete()
+	if len(sections) < 2 {
+		return analysis
+	}
+	rates := make([]float64, len(sections))
+	costs := make([]float64, len(sections))
+	arrivals := make([]float64, len(sections))
+	for i, section := range sections {
+		rates[i] = section.cost / ca.sections.length
+		costs[i] = section.cost
+		arrivals[i] = section.arrivals
+	}
+	halfWidth := TCritical(level, len(rates)-1) * math.Sqrt(sampleVariance(rates)/float64(len(rates)))
+	analysis.RateConfidence = [2]float64{analysis.Rate - halfWidth, analysis.Rate + halfWidth}
+	if ca.arrivals > 0 {
+		ratio := ratioEstimate("cost_per_customer", costs, arrivals, level)
+		halfWidth = (ratio.Confidence[1] - ratio.Confidence[0]) / 2
+		analysis.PerCustomerConfidence = [2]float64{analysis.PerCustomer - halfWidth, analysis.PerCustomer + halfWidth}
+	}
+	return analysis
+}
+
+// resetCosts starts an empty cost accumulator when costs are configured.
+func (sc *EnhancedStatisticsCollector) resetCosts() {
+	sc.costs = nil
+	if costEnabled(sc.config) {
+		sc.costs = newCostAccumulator(sc.config)
+	}
+}
+
+func (sc *EnhancedStatisticsCollector) calculateCost() {
+	sc.metrics.Cost = nil
+	if sc.costs != nil {
+		sc.metrics.Cost = sc.costs.analysis(confidenceLevel(sc.config))
+	}
+}
+
+// costValue returns the total, rate or per_customer cost, or NaN when no
+// costs are configured.
+func costValue(analysis *models.CostAnalysis, kind string) float64 {
+	if analysis == nil {
+		return math.NaN()
+	}
+	switch kind {
+	case "total":
+		return analysis.Total
+	case "rate":
+		return analysis.Rate
+	}
+	return analysis.PerCustomer
+}
@@ -0,0 +1,76 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func TestCostAnalysisHandComputed(t *testing.T) {
+	config := &models.SimulationConfig{
+		Servers: 2,
+		Cost: models.CostConfig{
+			WaitingCost:      1,
+			HoldingCost:      0.5,
+			ServerCost:       2,
+			ServiceCost:      3,
+			RejectionPenalty: 5,
+			Revenue:          4,
+		},
+	}
+	ca := newCostAccumulator(config)
+	// arrivals at 0.5, 1.5 and 2.5 are admitted; at 3.5 one is rejected and
+	// one of the two customers in service leaves
+	ca.advance(0, 0, 0.5)
+	ca.arrival(false)
+	ca.advance(0, 1, 1)
+	ca.arrival(false)
+	ca.advance(0, 2, 1)
+	ca.arrival(false)
+	ca.advance(1, 2, 1)
+	ca.arrival(true)
+	ca.completion()
+	ca.advance(0, 2, 0.5)
+
+	analysis := ca.analysis(0.95)
+	// the integrals of Q(t), Q(t)+B(t) and B(t) over [0, 4) are 1, 7 and 6
+	components := []struct {
+		name      string
+		got, want float64
+	}{
+		{"waiting", analysis.Waiting, 1 * 1},
+		{"holding", analysis.Holding, 0.5 * 7},
+		{"staffing", analysis.Staffing, 2 * 2 * 4},
+		{"service", analysis.Service, 3 * 6},
+		{"rejection", analysis.Rejection, 5},
+		{"revenue", analysis.Revenue, 4},
+		{"total", analysis.Total, 1 + 3.5 + 16 + 18 + 5 - 4},
+		{"rate", analysis.Rate, 39.5 / 4},
+		{"per customer", analysis.PerCustomer, 39.5 / 4},
+	}
+	for _, c := range components {
+		if math.Abs(c.got-c.want) > 1e-12 {
+			t.Errorf("%s cost %g, want %g", c.name, c.got, c.want)
+		}
+	}
+
+	// four unit sections costing 5.75, 9.25, 11.75 and 12.75 (the last with
+	// the penalty and revenue at 3.5); their squared deviations from 9.875
+	// sum to 29.1875, and t_{0.975,3} = 3.182446
+	sections := ca.sections.complete()
+	want := []float64{5.75, 9.25, 11.75, 12.75}
+	if len(sections) != len(want) {
+		t.Fatalf("%d complete sections, want %d", len(sections), len(want))
+	}
+	for i, section := range sections {
+		if math.Abs(section.cost-want[i]) > 1e-12 || section.arrivals != 1 {
+			t.Errorf("section %d: cost %g with %g arrivals, want %g with 1", i, section.cost, section.arrivals, want[i])
+		}
+	}
+	halfWidth := 3.182446 * math.Sqrt(29.1875/3/4)
+	for _, interval := range [][2]float64{analysis.RateConfidence, analysis.PerCustomerConfidence} {
+		if math.Abs(interval[0]-(9.875-halfWidth)) > 1e-5 || math.Abs(interval[1]-(9.875+halfWidth)) > 1e-5 {
+			t.Errorf("interval [%.6f, %.6f], want 9.875 +- %.6f", interval[0], interval[1], halfWidth)
+		}
+	}
+}
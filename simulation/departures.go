@@ -0,0 +1,157 @@
+package simulation
+
+import (
+	"bufio"
+	"des/models"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+const defaultDepartureLags = 5
+
+// autocorrelationAccumulator estimates lag-k autocorrelations of a series
+// in one pass, with the same estimator as lagAutocorrelation. It keeps only
+// the first and the last maxLag values and the lagged cross products.
+type autocorrelationAccumulator struct {
+	maxLag int
+	n      int
+	sum    float64
+	sumSq  float64
+	first  []float64
+	recent []float64
+	cross  []float64
+}
+
+func newAutocorrelationAccumulator(maxLag int) *autocorrelationAccumulator {
+	return &autocorrelationAccumulator{
+		maxLag: maxLag,
+		recent: make([]float64, maxLag),
+		cross:  make([]float64, maxLag),
+	}
+}
+
+func (ac *autocorrelationAccumulator) add(x float64) {
+	for k := 1; k <= ac.maxLag && k <= ac.n; k++ {
+		ac.cross[k-1] += x * ac.recent[(ac.n-k)%ac.maxLag]
+	}
+	if ac.n < ac.maxLag {
+		ac.first = append(ac.first, x)
+	}
+	if ac.maxLag > 0 {
+		ac.recent[ac.n%ac.maxLag] = x
+	}
+	ac.n++
+	ac.sum += x
+	ac.sumSq += x * x
+}
+
+// autocorrelation returns the lag-k sample autocorrelation
+// sum (x_i - m)(x_{i+k} - m) / sum (x_i - m)^2.
+func (ac *autocorrelationAccumulator) autocorrelation(k int) float64 {
+	if k < 1 || k > ac.maxLag || ac.n <= k {
+		return 0
+	}
+	n := float64(ac.n)
+	mean := ac.sum / n
+	denominator := ac.sumSq - n*mean*mean
+	if denominator <= 0 {
+		return 0
+	}
+	// sum of x_i for i <= n-k and for i > k
+	head, tail := ac.sum, ac.sum
+	for j := 1; j <= k; j++ {
+		head -= ac.recent[(ac.n-j)%ac.maxLag]
+		tail -= ac.first[j-1]
+	}
+	numerator := ac.cross[k-1] - mean*(head+tail) + float64(ac.n-k)*mean*mean
+	return numerator / denominator
+}
+
+func departureLags(config *models.SimulationConfig) int {
+	if config.Departures.Lags > 0 {
+		return config.Departures.Lags
+	}
+	return defaultDepartureLags
+}
+
+// recordDeparture adds the time since the previous departure to the
+// inter-departure statistics.
+func (sc *EnhancedStatisticsCollector) recordDeparture(t float64) {
+	if sc.departureSeen {
+		gap := t - sc.lastDeparture
+		sc.interDepartures.add(gap)
+		sc.departureCorrelation.add(gap)
+	}
+	sc.lastDeparture = t
+	sc.departureSeen = true
+}
+
+func (sc *EnhancedStatisticsCollector) resetDepartures() {
+	sc.interDepartures = welford{}
+	sc.departureCorrelation = newAutocorrelationAccumulator(departureLags(sc.config))
+	sc.departureSeen = false
+}
+
+func (sc *EnhancedStatisticsCollector) calculateDepartureProcess() {
+	sc.metrics.DepartureProcess = nil
+	moments := sc.interDepartures
+	if moments.n < 2 {
+		return
+	}
+	variance := moments.m2 / float64(moments.n-1)
+	process := &models.DepartureProcess{
+		Count:                  moments.n,
+		MeanInterDeparture:     moments.mean,
+		InterDepartureVariance: variance,
+		SCV:                    variance / (moments.mean * moments.mean),
+	}
+	if moments.mean > 0 {
+		process.Rate = 1 / moments.mean
+	}
+	for k := 1; k <= departureLags(sc.config); k++ {
+		process.Autocorrelations = append(process.Autocorrelations, sc.departureCorrelation.autocorrelation(k))
+	}
+	sc.metrics.DepartureProcess = process
+}
+
+// departureExport writes one departure timestamp per line, in the format
+// read back by random.arrival_trace.
+type departureExport struct {
+	file   *os.File
+	writer *bufio.Writer
+	path   string
+}
+
+func newDepartureExport(path string) (*departureExport, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create departure export: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	writer.WriteString("departure_time\n")
+	return &departureExport{file: file, writer: writer, path: path}, nil
+}
+
+func (de *departureExport) write(t float64) {
+	de.writer.WriteString(strconv.FormatFloat(t, 'g', -1, 64))
+	de.writer.WriteByte('\n')
+}
+
+func (de *departureExport) close() error {
+	if err := de.writer.Flush(); err != nil {
+		de.file.Close()
+		return fmt.Errorf("failed to write departure export: %v", err)
+	}
+	return de.file.Close()
+}
+
+// departureSCV is the metric accessor for the squared coefficient of
+// variation of inter-departure times.
+func departureSCV(process *models.DepartureProcess) float64 {
+	if process == nil {
+		return math.NaN()
+	}
+	return process.SCV
+}
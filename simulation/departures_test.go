@@ -0,0 +1,45 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAutocorrelationAccumulatorMatchesTwoPass(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ar := make([]float64, 500)
+	for i := 1; i < len(ar); i++ {
+		ar[i] = 0.8*ar[i-1] + rng.NormFloat64()
+	}
+	trend := make([]float64, 50)
+	for i := range trend {
+		trend[i] = 1000 + float64(i) + rng.ExpFloat64()
+	}
+
+	tests := []struct {
+		name   string
+		values []float64
+		maxLag int
+	}{
+		{"AR(1)", ar, 5},
+		{"offset trend", trend, 10},
+		{"series no longer than the lags", ar[:4], 5},
+		{"two values", []float64{1, 3}, 3},
+		{"constant", []float64{2, 2, 2, 2, 2, 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := newAutocorrelationAccumulator(tt.maxLag)
+			for _, x := range tt.values {
+				ac.add(x)
+			}
+			for k := 1; k <= tt.maxLag; k++ {
+				got, want := ac.autocorrelation(k), lagAutocorrelation(tt.values, k)
+				if math.Abs(got-want) > 1e-9 {
+					t.Errorf("lag %d: %.12f, want %.12f", k, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const (
+	designFullFactorial       = "full_factorial"
+	designFractionalFactorial = "fractional_factorial"
+	designLatinHypercube      = "latin_hypercube"
+
+	maxDesignFactors = 12
+)
+
+// ValidateDesign checks the design type, its size and the factor ranges.
+func ValidateDesign(config *models.SimulationConfig) error {
+	design := config.Design
+	k := len(design.Factors)
+	if k == 0 {
+		return fmt.Errorf("design lists no factors")
+	}
+	if k > maxDesignFactors {
+		return fmt.Errorf("design has %d factors, at most %d are supported", k, maxDesignFactors)
+	}
+	seen := make(map[string]bool)
+	for _, factor := range design.Factors {
+		if seen[factor.Field] {
+			return fmt.Errorf("factor %q is listed twice", factor.Field)
+		}
+		seen[factor.Field] = true
+		if factor.High <= factor.Low {
+			return fmt.Errorf("factor %q needs low < high", factor.Field)
+		}
+	}
+	switch design.Type {
+	case "", designFullFactorial:
+	case designFractionalFactorial:
+		if design.Resolution != 3 && design.Resolution != 4 {
+			return fmt.Errorf("fractional factorial resolution must be 3 or 4")
+		}
+	case designLatinHypercube:
+		if design.Samples <= k {
+			return fmt.Errorf("latin hypercube needs more samples than factors (%d)", k)
+		}
+	default:
+		return fmt.Errorf("unknown design type %q", design.Type)
+	}
+	if len(design.Responses) == 0 {
+		return fmt.Errorf("design lists no responses")
+	}
+	for _, name := range design.Responses {
+		if _, err := LookupMetric(config, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildDesign generates the runs of the configured design. Two-level designs
+// set every factor to its low or high value; a Latin hypercube splits each
+// factor range into one stratum per sample and draws one value from each.
+func BuildDesign(config *models.SimulationConfig) *models.ExperimentDesign {
+	design := &models.ExperimentDesign{
+		Type:    config.Design.Type,
+		Factors: config.Design.Factors,
+	}
+	if design.Type == "" {
+		design.Type = designFullFactorial
+	}
+
+	switch design.Type {
+	case designLatinHypercube:
+		design.Coded = latinHypercube(len(design.Factors), config.Design.Samples, ResolveSeed(config.Random.Seed))
+	case designFractionalFactorial:
+		design.Coded, design.Generators = fractionalFactorial(len(design.Factors), config.Design.Resolution)
+		design.Resolution = designResolution(design.Coded)
+	default:
+		design.Coded = fullFactorial(len(design.Factors))
+	}
+
+	for r, row := range design.Coded {
+		settings := make([]models.SweepSetting, len(design.Factors))
+		for j, factor := range design.Factors {
+			value := factor.Low + (row[j]+1)/2*(factor.High-factor.Low)
+			if factor.Integer {
+				value = math.Round(value)
+				design.Coded[r][j] = 2*(value-factor.Low)/(factor.High-factor.Low) - 1
+			}
+			settings[j] = models.SweepSetting{Field: factor.Field, Value: strconv.FormatFloat(value, 'g', 12, 64)}
+		}
+		design.Settings = append(design.Settings, settings)
+	}
+	return design
+}
+
+// fullFactorial returns the 2^k full factorial in standard order:
+// factor j is at its high level when bit j of the run index is set.
+func fullFactorial(k int) [][]float64 {
+	rows := make([][]float64, 1<<k)
+	for r := range rows {
+		rows[r] = make([]float64, k)
+		for j := 0; j < k; j++ {
+			rows[r][j] = -1
+			if r&(1<<j) != 0 {
+				rows[r][j] = 1
+			}
+		}
+	}
+	return rows
+}
+
+// fractionalFactorial returns a 2^(k-p) design of at least the requested
+// resolution in as few runs as possible. The first m factors form a full
+// factorial and each further factor is set to an interaction of them: any
+// interaction of two or more for resolution III, odd interactions of three
+// or more for resolution IV, which makes every word of the defining relation
+// even and at least four letters long.
+func fractionalFactorial(k, resolution int) ([][]float64, []string) {
+	for m := 1; m < k; m++ {
+		var candidates []int
+		for size := 2; size <= m; size++ {
+			if resolution == 4 && size%2 == 0 {
+				continue
+			}
+			for mask := 1; mask < 1<<m; mask++ {
+				if bits.OnesCount(uint(mask)) == size {
+					candidates = append(candidates, mask)
+				}
+			}
+		}
+		if len(candidates) < k-m {
+			continue
+		}
+
+		rows := fullFactorial(m)
+		var generators []string
+		for j, mask := range candidates[:k-m] {
+			generators = append(generators, fmt.Sprintf("%s = %s", factorLetter(m+j), maskLetters(mask)))
+		}
+		for r := range rows {
+			for _, mask := range candidates[:k-m] {
+				product := 1.0
+				for b := 0; b < m; b++ {
+					if mask&(1<<b) != 0 {
+						product *= rows[r][b]
+					}
+				}
+				rows[r] = append(rows[r], product)
+			}
+		}
+		return rows, generators
+	}
+	return fullFactorial(k), nil
+}
+
+// designResolution is the length of the shortest word of the defining
+// relation: the fewest factors whose columns multiply to the identity.
+func designResolution(rows [][]float64) int {
+	k := len(rows[0])
+	best := 0
+	for mask := 1; mask < 1<<k; mask++ {
+		size := bits.OnesCount(uint(mask))
+		if best > 0 && size >= best {
+			continue
+		}
+		identity := true
+		for _, row := range rows {
+			product := 1.0
+			for j := 0; j < k; j++ {
+				if mask&(1<<j) != 0 {
+					product *= row[j]
+				}
+			}
+			if product != 1 {
+				identity = false
+				break
+			}
+		}
+		if identity {
+			best = size
+		}
+	}
+	return best
+}
+
+// latinHypercube returns n coded runs in which every factor takes one value
+// from each of n equal strata, with the strata paired at random.
+func latinHypercube(k, n int, seed int64) [][]float64 {
+	stream := NewRandomStream(DeriveSeed(seed, designStream), false)
+	rows := make([][]float64, n)
+	for r := range rows {
+		rows[r] = make([]float64, k)
+	}
+	for j := 0; j < k; j++ {
+		for r, stratum := range stream.rng.Perm(n) {
+			u := (float64(stratum) + stream.Float64()) / float64(n)
+			rows[r][j] = 2*u - 1
+		}
+	}
+	return rows
+}
+
+func factorLetter(j int) string {
+	return string(rune('A' + j))
+}
+
+func maskLetters(mask int) string {
+	var letters strings.Builder
+	for b := 0; mask>>b != 0; b++ {
+		if mask&(1<<b) != 0 {
+			letters.WriteString(factorLetter(b))
+		}
+	}
+	return letters.String()
+}
+
+// designTerm is a main effect or two-factor interaction column of the model.
+type designTerm struct {
+	label   string
+	aliases []string
+	column  []float64
+}
+
+// designTerms returns the main effects and the two-factor interactions. In a
+// fractional design an interaction whose column equals that of an earlier
+// term is listed as an alias of that term instead. A Latin hypercube only
+// gets interactions when it has runs to spare.
+func designTerms(design *models.ExperimentDesign) []*designTerm {
+	k := len(design.Factors)
+	column := func(factors ...int) []float64 {
+		values := make([]float64, len(design.Coded))
+		for r, row := range design.Coded {
+			values[r] = 1
+			for _, j := range factors {
+				values[r] *= row[j]
+			}
+		}
+		return values
+	}
+
+	var terms []*designTerm
+	for j, factor := range design.Factors {
+		terms = append(terms, &designTerm{label: factor.Field, column: column(j)})
+	}
+	if design.Type == designLatinHypercube && len(design.Coded) <= 1+k+k*(k-1)/2 {
+		return terms
+	}
+
+	for i := 0; i < k; i++ {
+		for j := i + 1; j < k; j++ {
+			label := design.Factors[i].Field + " x " + design.Factors[j].Field
+			values := column(i, j)
+			merged := false
+			for _, term := range terms {
+				if sameColumn(term.column, values) {
+					term.aliases = append(term.aliases, label)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				terms = append(terms, &designTerm{label: label, column: values})
+			}
+		}
+	}
+	return terms
+}
+
+func sameColumn(a, b []float64) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// AnalyzeDesign fits each response by least squares on the coded factors and
+// reports every coefficient as an effect: twice the coefficient, the change
+// from the low to the high level. Standard errors come from the replication
+// variances when every run was replicated, and from the residuals otherwise.
+func AnalyzeDesign(design *models.ExperimentDesign, level float64) {
+	terms := designTerms(design)
+	design.Effects = nil
+	for m, response := range design.Sweep.Metrics {
+		design.Effects = append(design.Effects, fitEffects(design, terms, m, response, level))
+	}
+}
+
+// leastSquaresFit holds an ordinary least-squares fit of y on the columns of
+// the rows.
+type leastSquaresFit struct {
+	coefficients []float64
+	// inverse is (X'X)^-1
+	inverse [][]float64
+	// hat[j][i] is the weight of observation i in coefficient j
+	hat      [][]float64
+	residual []float64
+	rss, tss float64
+}
+
+// leastSquares fits y on the rows, which must include any intercept column.
+// It returns false when the columns are not linearly independent.
+func leastSquares(rows [][]float64, y []float64) (*leastSquaresFit, bool) {
+	p := len(rows[0])
+	// (X'X)^-1 column by column, then the coefficients (X'X)^-1 X'y
+	xtx := make([][]float64, p)
+	for a := range xtx {
+		xtx[a] = make([]float64, p)
+		for _, row := range rows {
+			for b := range row {
+				xtx[a][b] += row[a] * row[b]
+			}
+		}
+	}
+	fit := &leastSquaresFit{
+		coefficients: make([]float64, p),
+		inverse:      make([][]float64, p),
+		hat:          make([][]float64, p),
+		residual:     make([]float64, len(rows)),
+	}
+	for j := 0; j < p; j++ {
+		unit := make([]float64, p)
+		unit[j] = 1
+		solution, ok := solveLinearSystem(xtx, unit)
+		if !ok {
+			return nil, false
+		}
+		fit.inverse[j] = solution
+	}
+	for j := range fit.hat {
+		fit.hat[j] = make([]float64, len(rows))
+		for i, row := range rows {
+			for b := range row {
+				fit.hat[j][i] += fit.inverse[j][b] * row[b]
+			}
+			fit.coefficients[j] += fit.hat[j][i] * y[i]
+		}
+	}
+
+	mean := sampleMean(y)
+	for i, row := range rows {
+		fitted := 0.0
+		for b := range row {
+			fitted += fit.coefficients[b] * row[b]
+		}
+		fit.residual[i] = y[i] - fitted
+		fit.rss += fit.residual[i] * fit.residual[i]
+		fit.tss += (y[i] - mean) * (y[i] - mean)
+	}
+	return fit, true
+}
+
+func fitEffects(design *models.ExperimentDesign, terms []*designTerm, m int, response string, level float64) *models.ResponseEffects {
+	result := &models.ResponseEffects{Response: response}
+
+	var rows [][]float64
+	var y, variances []float64
+	replicated := true
+	freedom := 0
+	for r, point := range design.Sweep.Points {
+		metric := point.Metrics[m]
+		if metric == nil {
+			continue
+		}
+		row := []float64{1}
+		for _, term := range terms {
+			row = append(row, term.column[r])
+		}
+		rows = append(rows, row)
+		y = append(y, metric.Mean)
+		if point.Replications > 1 {
+			variances = append(variances, metric.StdDev*metric.StdDev/float64(point.Replications))
+			freedom += point.Replications - 1
+		} else {
+			replicated = false
+		}
+	}
+	p := len(terms) + 1
+	result.Points = len(rows)
+	if len(rows) < p {
+		result.Unfitted = fmt.Sprintf("%d runs produced the response, %d are needed", len(rows), p)
+		return result
+	}
+
+	fit, ok := leastSquares(rows, y)
+	if !ok {
+		result.Unfitted = "the factor columns are not independent"
+		return result
+	}
+	coefficients, inverse, hat, rss, tss := fit.coefficients, fit.inverse, fit.hat, fit.rss, fit.tss
+	result.RSquared = math.NaN()
+	if tss > 0 {
+		result.RSquared = 1 - rss/tss
+	}
+
+	if !replicated {
+		freedom = len(rows) - p
+	}
+	result.Freedom = freedom
+	for t, term := range terms {
+		j := t + 1
+		estimate := &models.EffectEstimate{
+			Term:     term.label,
+			Aliases:  term.aliases,
+			Effect:   2 * coefficients[j],
+			StdError: math.NaN(),
+		}
+		variance := math.NaN()
+		switch {
+		case replicated && freedom > 0:
+			variance = 0
+			for i, v := range variances {
+				variance += hat[j][i] * hat[j][i] * v
+			}
+		case !replicated && freedom > 0:
+			variance = rss / float64(freedom) * inverse[j][j]
+		}
+		if !math.IsNaN(variance) {
+			estimate.StdError = 2 * math.Sqrt(variance)
+			halfWidth := TCritical(level, freedom) * estimate.StdError
+			estimate.Confidence = [2]float64{estimate.Effect - halfWidth, estimate.Effect + halfWidth}
+			estimate.HasConfidence = true
+		}
+		result.Effects = append(result.Effects, estimate)
+	}
+	return result
+}
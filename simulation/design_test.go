@@ -0,0 +1,94 @@
+package simulation
+
+import (
+	"des/models"
+	"reflect"
+	"testing"
+)
+
+func TestFractionalFactorial(t *testing.T) {
+	tests := []struct {
+		k, resolution int
+		runs          int
+		generators    []string
+	}{
+		{3, 3, 4, []string{"C = AB"}},
+		{4, 3, 8, []string{"D = AB"}},
+		{4, 4, 8, []string{"D = ABC"}},
+		{5, 3, 8, []string{"D = AB", "E = AC"}},
+		{5, 4, 16, []string{"E = ABC"}},
+		{6, 4, 16, []string{"E = ABC", "F = ABD"}},
+		{7, 3, 8, []string{"D = AB", "E = AC", "F = BC", "G = ABC"}},
+	}
+	for _, tt := range tests {
+		rows, generators := fractionalFactorial(tt.k, tt.resolution)
+		if len(rows) != tt.runs {
+			t.Errorf("k=%d resolution %d: %d runs, want %d", tt.k, tt.resolution, len(rows), tt.runs)
+		}
+		if !reflect.DeepEqual(generators, tt.generators) {
+			t.Errorf("k=%d resolution %d: generators %v, want %v", tt.k, tt.resolution, generators, tt.generators)
+		}
+		if got := designResolution(rows); got != tt.resolution {
+			t.Errorf("k=%d resolution %d: design has resolution %d", tt.k, tt.resolution, got)
+		}
+		// every column is balanced and orthogonal to every other
+		for i := 0; i < tt.k; i++ {
+			for j := i; j < tt.k; j++ {
+				sum := 0.0
+				for _, row := range rows {
+					product := row[i]
+					if j != i {
+						product *= row[j]
+					}
+					sum += product
+				}
+				if sum != 0 {
+					t.Errorf("k=%d resolution %d: columns %d and %d not orthogonal", tt.k, tt.resolution, i, j)
+				}
+			}
+		}
+	}
+
+	if got := designResolution(fullFactorial(4)); got != 0 {
+		t.Errorf("full factorial has resolution %d, want 0 (no defining relation)", got)
+	}
+}
+
+func TestDesignTermsAliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		k          int
+		resolution int // 0 for the full factorial
+		aliases    map[string][]string
+	}{
+		{"full factorial", 3, 0, map[string][]string{
+			"A": nil, "B": nil, "C": nil, "A x B": nil, "A x C": nil, "B x C": nil,
+		}},
+		{"resolution III, C = AB", 3, 3, map[string][]string{
+			"A": {"B x C"}, "B": {"A x C"}, "C": {"A x B"},
+		}},
+		{"resolution IV, D = ABC", 4, 4, map[string][]string{
+			"A": nil, "B": nil, "C": nil, "D": nil,
+			"A x B": {"C x D"}, "A x C": {"B x D"}, "A x D": {"B x C"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			design := &models.ExperimentDesign{Type: designFullFactorial, Coded: fullFactorial(tt.k)}
+			if tt.resolution > 0 {
+				design.Type = designFractionalFactorial
+				design.Coded, _ = fractionalFactorial(tt.k, tt.resolution)
+			}
+			for j := 0; j < tt.k; j++ {
+				design.Factors = append(design.Factors, models.DesignFactor{Field: factorLetter(j)})
+			}
+			got := make(map[string][]string)
+			for _, term := range designTerms(design) {
+				got[term.label] = term.aliases
+			}
+			if !reflect.DeepEqual(got, tt.aliases) {
+				t.Errorf("terms and aliases %v, want %v", got, tt.aliases)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p, df float64
+		want  float64
+	}{
+		{0.975, 1, 12.706205},
+		{0.975, 2, 4.302653},
+		{0.975, 5, 2.570582},
+		{0.975, 10, 2.228139},
+		{0.975, 30, 2.042272},
+		{0.95, 10, 1.812461},
+		{0.995, 20, 2.845340},
+		{0.9, 4, 1.533206},
+		{0.025, 10, -2.228139},
+		{0.5, 7, 0},
+		{0.975, math.Inf(1), 1.959964},
+	}
+	for _, tt := range tests {
+		got := StudentTQuantile(tt.p, tt.df)
+		if math.Abs(got-tt.want) > 1e-5 {
+			t.Errorf("StudentTQuantile(%g, %g) = %.6f, want %.6f", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestTCritical(t *testing.T) {
+	tests := []struct {
+		level float64
+		df    int
+		want  float64
+	}{
+		{0.95, 9, 2.262157},
+		{0.95, 1, 12.706205},
+		{0.90, 10, 1.812461},
+		{0.99, 20, 2.845340},
+		{0.99, 120, 2.617421},
+	}
+	for _, tt := range tests {
+		got := TCritical(tt.level, tt.df)
+		if math.Abs(got-tt.want) > 1e-5 {
+			t.Errorf("TCritical(%g, %d) = %.6f, want %.6f", tt.level, tt.df, got, tt.want)
+		}
+	}
+	if got := TCritical(0.95, 0); !math.IsNaN(got) {
+		t.Errorf("TCritical(0.95, 0) = %g, want NaN", got)
+	}
+}
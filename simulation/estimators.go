@@ -0,0 +1,122 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+)
+
+const defaultConfidenceLevel = 0.95
+
+// confidenceLevel returns the configured confidence level or the default.
+func confidenceLevel(config *models.SimulationConfig) float64 {
+	level := config.OutputAnalysis.ConfidenceLevel
+	if level <= 0 || level >= 1 {
+		return defaultConfidenceLevel
+	}
+	return level
+}
+
+func sampleMean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sampleVariance returns the unbiased (n-1) sample variance.
+func sampleVariance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := sampleMean(values)
+	sumSq := 0.0
+	for _, v := range values {
+		sumSq += (v - mean) * (v - mean)
+	}
+	return sumSq / float64(len(values)-1)
+}
+
+// tConfidenceInterval returns the Student-t interval for the mean of iid
+// observations at the given confidence level.
+func tConfidenceInterval(values []float64, level float64) [2]float64 {
+	mean := sampleMean(values)
+	if len(values) < 2 {
+		return [2]float64{mean, mean}
+	}
+	halfWidth := TCritical(level, len(values)-1) * math.Sqrt(sampleVariance(values)/float64(len(values)))
+	return [2]float64{mean - halfWidth, mean + halfWidth}
+}
+
+// solveLinearSystem solves a*x = b by Gaussian elimination with partial
+// pivoting. It reports false when a is singular.
+func solveLinearSystem(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append([]float64{}, a[i]...), b[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, true
+}
+
+// buildHistogram counts values in equal-width bins spanning their range.
+func buildHistogram(values []float64, bins int) []models.HistogramBin {
+	if len(values) == 0 || bins < 1 {
+		return nil
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	width := (high - low) / float64(bins)
+	if width == 0 {
+		width = 1
+	}
+
+	histogram := make([]models.HistogramBin, bins)
+	for i := range histogram {
+		histogram[i].Lower = low + float64(i)*width
+		histogram[i].Upper = low + float64(i+1)*width
+	}
+	for _, v := range values {
+		i := int((v - low) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		histogram[i].Count++
+	}
+	return histogram
+}
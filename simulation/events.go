@@ -2,10 +2,8 @@ package simulation
 
 import (
 	"container/heap"
-	"math"
-	"math/rand"
-	"time"
 	"des/models"
+	"math"
 )
 
 type eventHeap []*models.Event
@@ -63,24 +61,42 @@ func (el *EventList) Size() int {
 }
 
 type EventManager struct {
-	eventList *EventList
-	rng       *rand.Rand
-	config    *models.SimulationConfig
+	eventList  *EventList
+	arrivals   *RandomStream
+	services   *RandomStream
+	config     *models.SimulationConfig
+	seed       int64
+	traceIndex int
+	traceLast  float64
 }
 
 func NewEventManager(config *models.SimulationConfig) *EventManager {
-	seed := config.Random.Seed
-	if seed == -1 {
-		seed = time.Now().UnixNano()
-	}
+	seed := ResolveSeed(config.Random.Seed)
 
 	return &EventManager{
 		eventList: NewEventList(),
-		rng:       rand.New(rand.NewSource(seed)),
+		arrivals:  NewRandomStream(DeriveSeed(seed, arrivalStream), config.Random.Antithetic),
+		services:  NewRandomStream(DeriveSeed(seed, serviceStream), config.Random.Antithetic),
 		config:    config,
+		seed:      seed,
 	}
 }
 
+// reset clears the event list and restarts the random streams and the
+// arrival trace, so a reinitialized run repeats from the beginning.
+func (em *EventManager) reset() {
+	em.eventList = NewEventList()
+	em.arrivals = NewRandomStream(DeriveSeed(em.seed, arrivalStream), em.config.Random.Antithetic)
+	em.services = NewRandomStream(DeriveSeed(em.seed, serviceStream), em.config.Random.Antithetic)
+	em.traceIndex = 0
+	em.traceLast = 0
+}
+
+// Seed returns the seed of the run, with a time-based seed resolved.
+func (em *EventManager) Seed() int64 {
+	return em.seed
+}
+
 func (em *EventManager) ScheduleEvent(eventType models.EventType, timestamp float64, customer *models.Customer) {
 	event := &models.Event{
 		Type:      eventType,
@@ -98,44 +114,78 @@ func (em *EventManager) PeekNextEvent() *models.Event {
 	return em.eventList.Peek()
 }
 
-func (em *EventManager) GenerateExponential(rate float64) float64 {
+func (em *EventManager) GenerateExponential(stream *RandomStream, rate float64) float64 {
 	if rate <= 0 {
 		return 1.0
 	}
-	u := em.rng.Float64()
+	u := stream.Float64()
 	for u == 0.0 || u == 1.0 {
-		u = em.rng.Float64()
+		u = stream.Float64()
 	}
 	return -math.Log(1.0-u) / rate
 }
 
-func (em *EventManager) GenerateUniform(min, max float64) float64 {
-	return min + em.rng.Float64()*(max-min)
+func (em *EventManager) GenerateUniform(stream *RandomStream, min, max float64) float64 {
+	return min + stream.Float64()*(max-min)
 }
 
 func (em *EventManager) GenerateConstant(value float64) float64 {
 	return value
 }
 
+// ArrivalDistribution returns the interarrival distribution, falling back
+// to random.distribution.
+func ArrivalDistribution(config *models.SimulationConfig) string {
+	if config.Random.ArrivalDistribution != "" {
+		return config.Random.ArrivalDistribution
+	}
+	return config.Random.Distribution
+}
+
+// ServiceDistribution returns the service-time distribution, falling back
+// to random.distribution.
+func ServiceDistribution(config *models.SimulationConfig) string {
+	if config.Random.ServiceDistribution != "" {
+		return config.Random.ServiceDistribution
+	}
+	return config.Random.Distribution
+}
+
 func (em *EventManager) GetInterarrivalTime() float64 {
-	switch em.config.Random.Distribution {
+	switch ArrivalDistribution(em.config) {
 	case "uniform":
-		return em.GenerateUniform(0.5/em.config.ArrivalRate, 1.5/em.config.ArrivalRate)
+		return em.GenerateUniform(em.arrivals, 0.5/em.config.ArrivalRate, 1.5/em.config.ArrivalRate)
 	case "constant":
 		return 1.0 / em.config.ArrivalRate
+	case "trace":
+		return em.nextTraceInterarrival()
 	default:
-		return em.GenerateExponential(em.config.ArrivalRate)
+		return em.GenerateExponential(em.arrivals, em.config.ArrivalRate)
 	}
 }
 
+// nextTraceInterarrival replays the recorded arrival timestamps and returns
+// +Inf once the trace is exhausted.
+func (em *EventManager) nextTraceInterarrival() float64 {
+	times := em.config.Random.ArrivalTimes
+	if em.traceIndex >= len(times) {
+		return math.Inf(1)
+	}
+	t := times[em.traceIndex]
+	em.traceIndex++
+	gap := math.Max(0, t-em.traceLast)
+	em.traceLast = t
+	return gap
+}
+
 func (em *EventManager) GetServiceTime() float64 {
-	switch em.config.Random.Distribution {
+	switch ServiceDistribution(em.config) {
 	case "uniform":
-		return em.GenerateUniform(0.5/em.config.ServiceRate, 1.5/em.config.ServiceRate)
+		return em.GenerateUniform(em.services, 0.5/em.config.ServiceRate, 1.5/em.config.ServiceRate)
 	case "constant":
 		return 1.0 / em.config.ServiceRate
 	default:
-		return em.GenerateExponential(em.config.ServiceRate)
+		return em.GenerateExponential(em.services, em.config.ServiceRate)
 	}
 }
 
@@ -149,4 +199,4 @@ func (em *EventManager) ClearEvents() {
 
 func (em *EventManager) GetEventCount() int {
 	return em.eventList.Size()
-}
\ No newline at end of file
+}
@@ -0,0 +1,389 @@
+package simulation
+
+import (
+	"des/models"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMetamodelDegree = 2
+	maxMetamodelDegree     = 3
+)
+
+// ValidateMetamodel checks the degree and that the response is one of the
+// metrics the run reports.
+func ValidateMetamodel(config *models.SimulationConfig, metrics []string) error {
+	metamodel := config.Metamodel
+	if metamodel.Degree < 0 || metamodel.Degree > maxMetamodelDegree {
+		return fmt.Errorf("metamodel degree must be between 1 and %d, or 0 for the default %d", maxMetamodelDegree, defaultMetamodelDegree)
+	}
+	for _, name := range metrics {
+		if name == metamodel.Response {
+			return nil
+		}
+	}
+	return fmt.Errorf("metamodel response %q is not among the reported metrics %s", metamodel.Response, strings.Join(metrics, ", "))
+}
+
+// FitMetamodel fits a polynomial in the numeric fields of a sweep to the mean
+// of the metamodel response at each point. Factors are coded to [-1, 1] over
+// the range they were swept, and the model has every term up to the
+// configured total degree except powers that the number of distinct levels
+// of a factor cannot identify, such as the square of a two-level factor.
+//
+// Goodness of fit is reported as R^2, adjusted R^2, the residual standard
+// error and the predicted R^2 of leave-one-out cross-validation, computed
+// from the PRESS residuals e_i / (1 - h_ii).
+func FitMetamodel(config *models.SimulationConfig, result *models.SweepResult) (*models.Metamodel, error) {
+	response := -1
+	for m, name := range result.Metrics {
+		if name == config.Metamodel.Response {
+			response = m
+		}
+	}
+	if response < 0 {
+		return nil, fmt.Errorf("metamodel response %q was not measured", config.Metamodel.Response)
+	}
+	model := &models.Metamodel{
+		Response:        config.Metamodel.Response,
+		Degree:          config.Metamodel.Degree,
+		ConfidenceLevel: result.ConfidenceLevel,
+	}
+	if model.Degree == 0 {
+		model.Degree = defaultMetamodelDegree
+	}
+
+	// numeric settings of every point, factor by factor
+	settings := make([][]float64, len(result.Fields))
+	var levels []int
+	var factors []int
+	for f, field := range result.Fields {
+		distinct := make(map[float64]bool)
+		settings[f] = make([]float64, len(result.Points))
+		for i, point := range result.Points {
+			value, err := strconv.ParseFloat(point.Settings[f].Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("field %s has the non-numeric value %q", field, point.Settings[f].Value)
+			}
+			settings[f][i] = value
+			distinct[value] = true
+		}
+		if len(distinct) < 2 {
+			continue
+		}
+		factor := models.MetamodelFactor{Field: field, Low: math.Inf(1), High: math.Inf(-1)}
+		for value := range distinct {
+			factor.Low, factor.High = math.Min(factor.Low, value), math.Max(factor.High, value)
+		}
+		model.Factors = append(model.Factors, factor)
+		levels = append(levels, len(distinct))
+		factors = append(factors, f)
+	}
+	if len(model.Factors) == 0 {
+		return nil, fmt.Errorf("no swept field takes more than one value")
+	}
+	model.Terms = metamodelTerms(model.Factors, levels, model.Degree)
+
+	var rows [][]float64
+	var y []float64
+	for i, point := range result.Points {
+		metric := point.Metrics[response]
+		if metric == nil || math.IsNaN(metric.Mean) {
+			continue
+		}
+		coded := make([]float64, len(factors))
+		for j, f := range factors {
+			coded[j] = codeFactor(model.Factors[j], settings[f][i])
+		}
+		rows = append(rows, termValues(model.Terms, coded))
+		y = append(y, metric.Mean)
+	}
+	p := len(model.Terms)
+	model.Points = len(rows)
+	if len(rows) < p {
+		return nil, fmt.Errorf("%d points produced %s, a degree %d model needs %d", len(rows), model.Response, model.Degree, p)
+	}
+	fit, ok := leastSquares(rows, y)
+	if !ok {
+		return nil, fmt.Errorf("the terms of the degree %d model are not independent on these points", model.Degree)
+	}
+
+	model.Freedom = len(rows) - p
+	model.RSquared, model.AdjustedRSquared, model.PredictedRSquared = math.NaN(), math.NaN(), math.NaN()
+	model.ResidualStdError = math.NaN()
+	if fit.tss > 0 {
+		model.RSquared = 1 - fit.rss/fit.tss
+		press := 0.0
+		for i, row := range rows {
+			leverage := 0.0
+			for j := range row {
+				leverage += row[j] * fit.hat[j][i]
+			}
+			press += math.Pow(fit.residual[i]/(1-leverage), 2)
+		}
+		// a point with leverage 1 cannot be left out, and press is then Inf
+		if !math.IsInf(press, 0) && !math.IsNaN(press) {
+			model.PredictedRSquared = 1 - press/fit.tss
+		}
+	}
+	if model.Freedom > 0 {
+		variance := fit.rss / float64(model.Freedom)
+		model.ResidualStdError = math.Sqrt(variance)
+		if fit.tss > 0 {
+			model.AdjustedRSquared = 1 - variance/(fit.tss/float64(len(rows)-1))
+		}
+		model.Covariance = make([][]float64, p)
+		for j := range model.Covariance {
+			model.Covariance[j] = make([]float64, p)
+			for b := range model.Covariance[j] {
+				model.Covariance[j][b] = variance * fit.inverse[j][b]
+			}
+		}
+	}
+	for j, term := range model.Terms {
+		term.Coefficient = fit.coefficients[j]
+		term.StdError = math.NaN()
+		if model.Covariance != nil {
+			term.StdError = math.Sqrt(model.Covariance[j][j])
+		}
+	}
+	return model, nil
+}
+
+// metamodelTerms returns the intercept and every product of factor powers of
+// total degree up to degree, lowest degree first, leaving out powers of a
+// factor that reach its number of levels.
+func metamodelTerms(factors []models.MetamodelFactor, levels []int, degree int) []*models.MetamodelTerm {
+	terms := []*models.MetamodelTerm{{Label: "intercept", Powers: make([]int, len(factors))}}
+	var byDegree [][]int
+	var extend func(powers []int, from, remaining int)
+	extend = func(powers []int, from, remaining int) {
+		if powerSum(powers) > 0 {
+			byDegree = append(byDegree, append([]int{}, powers...))
+		}
+		for j := from; j < len(factors) && remaining > 0; j++ {
+			if powers[j]+1 >= levels[j] {
+				continue
+			}
+			powers[j]++
+			extend(powers, j, remaining-1)
+			powers[j]--
+		}
+	}
+	extend(make([]int, len(factors)), 0, degree)
+	sort.SliceStable(byDegree, func(a, b int) bool { return powerSum(byDegree[a]) < powerSum(byDegree[b]) })
+
+	for _, powers := range byDegree {
+		var parts []string
+		for j, power := range powers {
+			switch {
+			case power == 1:
+				parts = append(parts, factors[j].Field)
+			case power > 1:
+				parts = append(parts, fmt.Sprintf("%s^%d", factors[j].Field, power))
+			}
+		}
+		terms = append(terms, &models.MetamodelTerm{Label: strings.Join(parts, " x "), Powers: powers})
+	}
+	return terms
+}
+
+func powerSum(powers []int) int {
+	total := 0
+	for _, power := range powers {
+		total += power
+	}
+	return total
+}
+
+// codeFactor maps a value of the factor's range to [-1, 1].
+func codeFactor(factor models.MetamodelFactor, value float64) float64 {
+	return 2*(value-factor.Low)/(factor.High-factor.Low) - 1
+}
+
+// termValues evaluates every term at the coded factor values.
+func termValues(terms []*models.MetamodelTerm, coded []float64) []float64 {
+	values := make([]float64, len(terms))
+	for t, term := range terms {
+		values[t] = 1
+		for j, power := range term.Powers {
+			values[t] *= math.Pow(coded[j], float64(power))
+		}
+	}
+	return values
+}
+
+// Predict evaluates the metamodel at the given settings, which must set
+// every factor. The interval is the confidence interval of the expected
+// response, from the coefficient covariance; settings outside the fitted
+// range are listed as extrapolated.
+func Predict(model *models.Metamodel, settings []models.SweepSetting) (*models.MetamodelPrediction, error) {
+	values := make(map[string]float64)
+	for _, setting := range settings {
+		value, err := strconv.ParseFloat(setting.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q of %s is not a number", setting.Value, setting.Field)
+		}
+		values[setting.Field] = value
+	}
+	prediction := &models.MetamodelPrediction{
+		Response:   model.Response,
+		StdError:   math.NaN(),
+		Confidence: [2]float64{math.NaN(), math.NaN()},
+	}
+	coded := make([]float64, len(model.Factors))
+	for j, factor := range model.Factors {
+		value, ok := values[factor.Field]
+		if !ok {
+			return nil, fmt.Errorf("no value given for %s", factor.Field)
+		}
+		delete(values, factor.Field)
+		coded[j] = codeFactor(factor, value)
+		if value < factor.Low || value > factor.High {
+			prediction.Extrapolated = append(prediction.Extrapolated, factor.Field)
+		}
+		prediction.Settings = append(prediction.Settings, models.SweepSetting{Field: factor.Field, Value: strconv.FormatFloat(value, 'g', -1, 64)})
+	}
+	for field := range values {
+		return nil, fmt.Errorf("%s is not a factor of the metamodel", field)
+	}
+
+	row := termValues(model.Terms, coded)
+	for t, term := range model.Terms {
+		prediction.Mean += term.Coefficient * row[t]
+	}
+	if model.Covariance != nil {
+		variance := 0.0
+		for a := range row {
+			for b := range row {
+				variance += row[a] * model.Covariance[a][b] * row[b]
+			}
+		}
+		prediction.StdError = math.Sqrt(variance)
+		halfWidth := TCritical(model.ConfidenceLevel, model.Freedom) * prediction.StdError
+		prediction.Confidence = [2]float64{prediction.Mean - halfWidth, prediction.Mean + halfWidth}
+		prediction.HasConfidence = true
+	}
+	return prediction, nil
+}
+
+type metamodelJSONFactor struct {
+	Field string  `json:"field"`
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+}
+
+type metamodelJSONTerm struct {
+	Label       string   `json:"label"`
+	Powers      []int    `json:"powers"`
+	Coefficient float64  `json:"coefficient"`
+	StdError    *float64 `json:"std_error"`
+}
+
+type metamodelJSON struct {
+	Response          string                `json:"response"`
+	Degree            int                   `json:"degree"`
+	Points            int                   `json:"points"`
+	Freedom           int                   `json:"freedom"`
+	ConfidenceLevel   float64               `json:"confidence_level"`
+	RSquared          *float64              `json:"r_squared"`
+	AdjustedRSquared  *float64              `json:"adjusted_r_squared"`
+	PredictedRSquared *float64              `json:"predicted_r_squared"`
+	ResidualStdError  *float64              `json:"residual_std_error"`
+	Factors           []metamodelJSONFactor `json:"factors"`
+	Terms             []metamodelJSONTerm   `json:"terms"`
+	Covariance        [][]float64           `json:"covariance,omitempty"`
+}
+
+// WriteMetamodel saves the metamodel as JSON for the predict command and
+// records the file in the model.
+func WriteMetamodel(path string, model *models.Metamodel) error {
+	saved := metamodelJSON{
+		Response:          model.Response,
+		Degree:            model.Degree,
+		Points:            model.Points,
+		Freedom:           model.Freedom,
+		ConfidenceLevel:   model.ConfidenceLevel,
+		RSquared:          jsonNumber(model.RSquared),
+		AdjustedRSquared:  jsonNumber(model.AdjustedRSquared),
+		PredictedRSquared: jsonNumber(model.PredictedRSquared),
+		ResidualStdError:  jsonNumber(model.ResidualStdError),
+		Covariance:        model.Covariance,
+	}
+	for _, factor := range model.Factors {
+		saved.Factors = append(saved.Factors, metamodelJSONFactor{factor.Field, factor.Low, factor.High})
+	}
+	for _, term := range model.Terms {
+		saved.Terms = append(saved.Terms, metamodelJSONTerm{term.Label, term.Powers, term.Coefficient, jsonNumber(term.StdError)})
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metamodel: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write metamodel: %v", err)
+	}
+	model.OutputFile = path
+	return nil
+}
+
+// ReadMetamodel loads a metamodel saved by WriteMetamodel.
+func ReadMetamodel(path string) (*models.Metamodel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metamodel: %v", err)
+	}
+	var saved metamodelJSON
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse metamodel: %v", err)
+	}
+	model := &models.Metamodel{
+		Response:          saved.Response,
+		Degree:            saved.Degree,
+		Points:            saved.Points,
+		Freedom:           saved.Freedom,
+		ConfidenceLevel:   saved.ConfidenceLevel,
+		RSquared:          jsonValue(saved.RSquared),
+		AdjustedRSquared:  jsonValue(saved.AdjustedRSquared),
+		PredictedRSquared: jsonValue(saved.PredictedRSquared),
+		ResidualStdError:  jsonValue(saved.ResidualStdError),
+		Covariance:        saved.Covariance,
+		OutputFile:        path,
+	}
+	for _, factor := range saved.Factors {
+		if factor.High <= factor.Low {
+			return nil, fmt.Errorf("metamodel factor %s has an empty range", factor.Field)
+		}
+		model.Factors = append(model.Factors, models.MetamodelFactor{Field: factor.Field, Low: factor.Low, High: factor.High})
+	}
+	for _, term := range saved.Terms {
+		if len(term.Powers) != len(model.Factors) {
+			return nil, fmt.Errorf("metamodel term %q does not match the %d factors", term.Label, len(model.Factors))
+		}
+		model.Terms = append(model.Terms, &models.MetamodelTerm{
+			Label:       term.Label,
+			Powers:      term.Powers,
+			Coefficient: term.Coefficient,
+			StdError:    jsonValue(term.StdError),
+		})
+	}
+	if model.Covariance != nil && len(model.Covariance) != len(model.Terms) {
+		return nil, fmt.Errorf("metamodel covariance does not match its %d terms", len(model.Terms))
+	}
+	return model, nil
+}
+
+// jsonValue maps null back to NaN.
+func jsonValue(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
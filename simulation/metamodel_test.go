@@ -0,0 +1,129 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// polynomialSweep returns a sweep over every combination of the grids whose
+// response is f of the factor values coded to [-1, 1].
+func polynomialSweep(fields []string, grids [][]float64, f func(coded []float64) float64) *models.SweepResult {
+	result := &models.SweepResult{Fields: fields, Metrics: []string{"response"}, ConfidenceLevel: 0.95}
+	var visit func(values []float64)
+	visit = func(values []float64) {
+		if len(values) == len(grids) {
+			coded := make([]float64, len(values))
+			settings := make([]models.SweepSetting, len(values))
+			for j, value := range values {
+				grid := grids[j]
+				coded[j] = 2*(value-grid[0])/(grid[len(grid)-1]-grid[0]) - 1
+				settings[j] = models.SweepSetting{Field: fields[j], Value: strconv.FormatFloat(value, 'g', -1, 64)}
+			}
+			result.Points = append(result.Points, &models.SweepPoint{
+				Settings: settings,
+				Metrics:  []*models.MetricSummary{{Name: "response", Mean: f(coded)}},
+			})
+			return
+		}
+		for _, value := range grids[len(values)] {
+			visit(append(values, value))
+		}
+	}
+	visit(nil)
+	return result
+}
+
+func TestFitMetamodelRecoversPolynomial(t *testing.T) {
+	tests := []struct {
+		name     string
+		degree   int
+		fields   []string
+		grids    [][]float64
+		response func(c []float64) float64
+		want     map[string]float64
+	}{
+		{
+			"quadratic in two factors", 2,
+			[]string{"x", "y"},
+			[][]float64{{0, 1, 2, 3, 4}, {10, 20, 30}},
+			func(c []float64) float64 {
+				return 1 + 2*c[0] - 3*c[1] + 0.5*c[0]*c[0] + 4*c[0]*c[1] - c[1]*c[1]
+			},
+			map[string]float64{"intercept": 1, "x": 2, "y": -3, "x^2": 0.5, "x x y": 4, "y^2": -1},
+		},
+		{
+			"no square of a two-level factor", 2,
+			[]string{"x", "z"},
+			[][]float64{{1, 2, 3}, {0, 1}},
+			func(c []float64) float64 { return 7 - c[0] + 2*c[1] + 3*c[0]*c[0] - 0.25*c[0]*c[1] },
+			map[string]float64{"intercept": 7, "x": -1, "z": 2, "x^2": 3, "x x z": -0.25},
+		},
+		{
+			"cubic in one factor", 3,
+			[]string{"x"},
+			[][]float64{{0, 0.5, 1, 1.5, 2, 2.5}},
+			func(c []float64) float64 { return -2 + c[0] - c[0]*c[0] + 2.5*c[0]*c[0]*c[0] },
+			map[string]float64{"intercept": -2, "x": 1, "x^2": -1, "x^3": 2.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &models.SimulationConfig{Metamodel: models.MetamodelConfig{Response: "response", Degree: tt.degree}}
+			sweep := polynomialSweep(tt.fields, tt.grids, tt.response)
+			model, err := FitMetamodel(config, sweep)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(model.Terms) != len(tt.want) {
+				t.Errorf("%d terms, want %d", len(model.Terms), len(tt.want))
+			}
+			for _, term := range model.Terms {
+				want, ok := tt.want[term.Label]
+				if !ok {
+					t.Errorf("unexpected term %s", term.Label)
+					continue
+				}
+				if math.Abs(term.Coefficient-want) > 1e-9 {
+					t.Errorf("%s: coefficient %.12f, want %g", term.Label, term.Coefficient, want)
+				}
+			}
+			if model.Points != len(sweep.Points) || model.Freedom != len(sweep.Points)-len(tt.want) {
+				t.Errorf("%d points and %d degrees of freedom", model.Points, model.Freedom)
+			}
+			if math.Abs(model.RSquared-1) > 1e-12 || math.Abs(model.PredictedRSquared-1) > 1e-9 {
+				t.Errorf("R^2 %.12f, predicted R^2 %.12f, want 1", model.RSquared, model.PredictedRSquared)
+			}
+			if model.ResidualStdError > 1e-9 {
+				t.Errorf("residual standard error %g, want 0", model.ResidualStdError)
+			}
+
+			// between the grid points, and beyond the upper end of the first factor
+			for _, beyond := range []bool{false, true} {
+				var settings []models.SweepSetting
+				coded := make([]float64, len(tt.grids))
+				for j, grid := range tt.grids {
+					low, high := grid[0], grid[len(grid)-1]
+					value := low + 0.3*(high-low)
+					if beyond && j == 0 {
+						value = high + 0.5*(high-low)
+					}
+					coded[j] = 2*(value-low)/(high-low) - 1
+					settings = append(settings, models.SweepSetting{Field: tt.fields[j], Value: strconv.FormatFloat(value, 'g', -1, 64)})
+				}
+				prediction, err := Predict(model, settings)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.response(coded); math.Abs(prediction.Mean-want) > 1e-9 {
+					t.Errorf("prediction at %v: %.12f, want %.12f", settings, prediction.Mean, want)
+				}
+				if extrapolated := len(prediction.Extrapolated) > 0; extrapolated != beyond {
+					t.Errorf("prediction at %v: extrapolated %v", settings, prediction.Extrapolated)
+				}
+			}
+		})
+	}
+}
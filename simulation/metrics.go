@@ -0,0 +1,113 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MetricDefinition names a scalar field of ComprehensiveMetrics so experiments
+// can refer to it from configuration and command-line flags.
+type MetricDefinition struct {
+	Name  string
+	Label string
+	Value func(m *models.ComprehensiveMetrics) float64
+}
+
+var metricDefinitions = []MetricDefinition{
+	{"average_wait_time", "Average Wait Time", func(m *models.ComprehensiveMetrics) float64 { return m.AverageWaitTime }},
+	{"average_system_time", "Average System Time", func(m *models.ComprehensiveMetrics) float64 { return m.AverageSystemTime }},
+	{"average_queue_length", "Average Queue Length", func(m *models.ComprehensiveMetrics) float64 { return m.AverageQueueLength }},
+	{"average_in_system", "Average In System", func(m *models.ComprehensiveMetrics) float64 { return m.AverageInSystem }},
+	{"server_utilization", "Server Utilization", func(m *models.ComprehensiveMetrics) float64 { return m.ServerUtilization }},
+	{"throughput", "Throughput", func(m *models.ComprehensiveMetrics) float64 { return m.Throughput }},
+	{"queue_probability", "Queue Probability", func(m *models.ComprehensiveMetrics) float64 { return m.QueueProbability }},
+	{"blocking_probability", "Blocking Probability", func(m *models.ComprehensiveMetrics) float64 { return m.BlockingProbability }},
+	{"rejected_customers", "Rejected Customers", func(m *models.ComprehensiveMetrics) float64 { return float64(m.RejectedCustomers) }},
+	{"total_customers", "Total Customers", func(m *models.ComprehensiveMetrics) float64 { return float64(m.TotalCustomers) }},
+	{"wait_time_variance", "Wait Time Variance", func(m *models.ComprehensiveMetrics) float64 { return m.WaitTimeVariance }},
+	{"system_time_variance", "System Time Variance", func(m *models.ComprehensiveMetrics) float64 { return m.SystemTimeVariance }},
+	{"max_queue_length", "Max Queue Length", func(m *models.ComprehensiveMetrics) float64 { return float64(m.MaxQueueLength) }},
+	{"max_wait_time", "Max Wait Time", func(m *models.ComprehensiveMetrics) float64 { return m.MaxWaitTime }},
+	{"server_idle_time", "Server Idle Time", func(m *models.ComprehensiveMetrics) float64 { return m.ServerIdleTime }},
+	{"server_busy_time", "Server Busy Time", func(m *models.ComprehensiveMetrics) float64 { return m.ServerBusyTime }},
+	{"cv_wait_time", "CV Wait Time", func(m *models.ComprehensiveMetrics) float64 { return controlVariateValue(m.WaitTimeControlVariate) }},
+	{"cv_system_time", "CV System Time", func(m *models.ComprehensiveMetrics) float64 { return controlVariateValue(m.SystemTimeControlVariate) }},
+	{"mean_busy_period", "Mean Busy Period", func(m *models.ComprehensiveMetrics) float64 { return busyPeriodMean(m.BusyPeriods, true) }},
+	{"mean_idle_period", "Mean Idle Period", func(m *models.ComprehensiveMetrics) float64 { return busyPeriodMean(m.BusyPeriods, false) }},
+	{"interdeparture_scv", "Inter-departure SCV", func(m *models.ComprehensiveMetrics) float64 { return departureSCV(m.DepartureProcess) }},
+	{"total_cost", "Total Cost", func(m *models.ComprehensiveMetrics) float64 { return costValue(m.Cost, "total") }},
+	{"cost_rate", "Cost per Time Unit", func(m *models.ComprehensiveMetrics) float64 { return costValue(m.Cost, "rate") }},
+	{"cost_per_customer", "Cost per Customer", func(m *models.ComprehensiveMetrics) float64 { return costValue(m.Cost, "per_customer") }},
+	{"warmup_time", "Warm-up Time", func(m *models.ComprehensiveMetrics) float64 { return m.WarmupTime }},
+}
+
+// percentileValue returns NaN for a percentile the run did not produce; NaN
+// values are skipped when aggregating replications.
+func percentileValue(percentiles map[string]float64, key string) float64 {
+	if value, ok := percentiles[key]; ok {
+		return value
+	}
+	return math.NaN()
+}
+
+// busyPeriodMean returns the mean busy (or idle) period length, or NaN if the
+// run had no complete period.
+func busyPeriodMean(analysis *models.BusyPeriodAnalysis, busy bool) float64 {
+	if analysis == nil {
+		return math.NaN()
+	}
+	stats := analysis.Idle
+	if busy {
+		stats = analysis.Busy
+	}
+	if stats == nil {
+		return math.NaN()
+	}
+	return stats.Mean
+}
+
+func controlVariateValue(estimate *models.ControlVariateEstimate) float64 {
+	if estimate == nil {
+		return math.NaN()
+	}
+	return estimate.Estimate
+}
+
+// MetricDefinitions returns the scalar metrics of config in display order:
+// the fixed metrics, one entry per configured quantile level of wait time,
+// system time and queue length (e.g. wait_time_p99), and the attained
+// fraction of each SLA target (e.g. sla_wait_time_within_20).
+func MetricDefinitions(config *models.SimulationConfig) []MetricDefinition {
+	definitions := append([]MetricDefinition{}, metricDefinitions...)
+	for _, p := range quantileLevels(config) {
+		key := quantileKey(p)
+		suffix := quantileMetricSuffix(p)
+		definitions = append(definitions,
+			MetricDefinition{"wait_time_" + suffix, "Wait Time " + key + " Pct", func(m *models.ComprehensiveMetrics) float64 { return percentileValue(m.WaitTimePercentiles, key) }},
+			MetricDefinition{"system_time_" + suffix, "System Time " + key + " Pct", func(m *models.ComprehensiveMetrics) float64 { return percentileValue(m.SystemTimePercentiles, key) }},
+			MetricDefinition{"queue_length_" + suffix, "Queue Length " + key + " Pct", func(m *models.ComprehensiveMetrics) float64 { return percentileValue(m.QueueLengthPercentiles, key) }},
+		)
+	}
+	for i, target := range config.SLA.Targets {
+		index := i
+		definitions = append(definitions,
+			MetricDefinition{slaMetricName(target), "SLA " + slaName(target), func(m *models.ComprehensiveMetrics) float64 { return slaValue(m.SLA, index) }})
+	}
+	return definitions
+}
+
+func LookupMetric(config *models.SimulationConfig, name string) (MetricDefinition, error) {
+	definitions := MetricDefinitions(config)
+	for _, def := range definitions {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+	names := make([]string, len(definitions))
+	for i, def := range definitions {
+		names[i] = def.Name
+	}
+	return MetricDefinition{}, fmt.Errorf("unknown metric %q (valid: %s)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,205 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	constraintMax = "max"
+	constraintMin = "min"
+
+	minOptimizeReplications     = 5
+	defaultOptimizeReplications = 80
+)
+
+// ValidateOptimize checks the decision variable, the constraints and the
+// cost metric of an optimization.
+func ValidateOptimize(config *models.SimulationConfig) error {
+	optimize := config.Optimize
+	if optimize.Field == "" {
+		return fmt.Errorf("optimize needs a field")
+	}
+	if len(optimize.Constraints) == 0 {
+		return fmt.Errorf("optimize lists no constraints")
+	}
+	for _, constraint := range optimize.Constraints {
+		if _, err := LookupMetric(config, constraint.Metric); err != nil {
+			return err
+		}
+		switch constraint.Type {
+		case "", constraintMax, constraintMin:
+		default:
+			return fmt.Errorf("constraint type must be %s or %s, got %q", constraintMax, constraintMin, constraint.Type)
+		}
+	}
+	if optimize.CostMetric != "" {
+		if _, err := LookupMetric(config, optimize.CostMetric); err != nil {
+			return err
+		}
+	}
+	if optimize.MaxReplications < 0 {
+		return fmt.Errorf("max_replications must be non-negative")
+	}
+	return nil
+}
+
+// Optimize searches the candidate values of the decision variable for the
+// cheapest one whose metrics meet every constraint. Feasibility is assumed
+// to improve as the variable grows, as it does for servers, queue capacity
+// and service rate, so a binary search finds the smallest feasible value.
+// Without a cost metric that value is the cheapest. With one, the search
+// continues upwards while the mean cost keeps falling.
+//
+// A candidate is judged by the t-intervals of its replications: a
+// constraint is met when the whole interval is within the limit and missed
+// when none of it is. Undecided candidates get twice the replications, up to
+// max_replications, and are treated as infeasible if still undecided.
+func Optimize(config *models.SimulationConfig, points []*models.SweepPoint, progress ProgressFunc) (*models.OptimizationResult, error) {
+	values := make([]float64, len(points))
+	for i, point := range points {
+		value, err := strconv.ParseFloat(point.Settings[0].Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("candidate %q of %s is not a number", point.Settings[0].Value, config.Optimize.Field)
+		}
+		values[i] = value
+	}
+	sort.Sort(byValue{points, values})
+
+	result := &models.OptimizationResult{
+		Field:           config.Optimize.Field,
+		CostMetric:      config.Optimize.CostMetric,
+		ConfidenceLevel: confidenceLevel(config),
+	}
+	seed := ResolveSeed(config.Random.Seed)
+	evaluated := make(map[int]*models.OptimizationCandidate)
+	evaluate := func(i int) *models.OptimizationCandidate {
+		if candidate, ok := evaluated[i]; ok {
+			return candidate
+		}
+		pointConfig := points[i].Config
+		if pointConfig.Random.Seed == -1 {
+			pointConfig.Random.Seed = seed
+		}
+		candidate := evaluateCandidate(pointConfig, config.Optimize, points[i].Settings[0].Value)
+		evaluated[i] = candidate
+		result.Candidates = append(result.Candidates, candidate)
+		if progress != nil {
+			progress(len(result.Candidates), len(points))
+		}
+		return candidate
+	}
+
+	low, high := 0, len(points)-1
+	if !evaluate(high).Feasible {
+		return result, nil
+	}
+	for low < high {
+		mid := (low + high) / 2
+		if evaluate(mid).Feasible {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	result.Best = evaluate(low)
+
+	if config.Optimize.CostMetric != "" {
+		for i := low + 1; i < len(points); i++ {
+			next := evaluate(i)
+			if !next.Feasible || next.Cost == nil || result.Best.Cost == nil || next.Cost.Mean >= result.Best.Cost.Mean {
+				break
+			}
+			result.Best = next
+		}
+	}
+	return result, nil
+}
+
+type byValue struct {
+	points []*models.SweepPoint
+	values []float64
+}
+
+func (b byValue) Len() int           { return len(b.values) }
+func (b byValue) Less(i, j int) bool { return b.values[i] < b.values[j] }
+func (b byValue) Swap(i, j int) {
+	b.points[i], b.points[j] = b.points[j], b.points[i]
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+}
+
+// evaluateCandidate replicates one candidate until every constraint is
+// decided or the replication budget is spent.
+func evaluateCandidate(config *models.SimulationConfig, optimize models.OptimizeConfig, value string) *models.OptimizationCandidate {
+	budget := optimize.MaxReplications
+	if budget <= 0 {
+		budget = defaultOptimizeReplications
+	}
+	n := min(budget, max(config.Replications, minOptimizeReplications))
+
+	config = ResolveWarmup(config)
+	base := ResolveSeed(config.Random.Seed)
+	var seeds []int64
+	var runs []*models.ComprehensiveMetrics
+	for {
+		more := replicationSeeds(base, len(runs), n-len(runs))
+		batch := make([]*models.ComprehensiveMetrics, len(more))
+		runParallel(Workers(config), len(more), nil, func(i int) {
+			batch[i] = RunSilent(config, more[i], false).Metrics
+		})
+		seeds = append(seeds, more...)
+		runs = append(runs, batch...)
+
+		candidate := judgeCandidate(summarizeReplications(config, seeds, runs), optimize, value)
+		if candidate.Conclusive || n >= budget {
+			return candidate
+		}
+		n = min(budget, 2*n)
+	}
+}
+
+func judgeCandidate(summary *models.ReplicationSummary, optimize models.OptimizeConfig, value string) *models.OptimizationCandidate {
+	candidate := &models.OptimizationCandidate{
+		Value:        value,
+		Replications: summary.Replications,
+		Feasible:     true,
+	}
+	for _, constraint := range optimize.Constraints {
+		check := &models.ConstraintCheck{
+			Metric:     constraint.Metric,
+			Type:       constraint.Type,
+			Limit:      constraint.Limit,
+			Estimate:   math.NaN(),
+			Confidence: [2]float64{math.NaN(), math.NaN()},
+		}
+		if check.Type == "" {
+			check.Type = constraintMax
+		}
+		if metric := FindMetric(summary, constraint.Metric); metric != nil {
+			check.Estimate, check.Confidence = metric.Mean, metric.Confidence
+			if check.Type == constraintMax {
+				check.Met = metric.Confidence[1] <= constraint.Limit
+				check.Conclusive = check.Met || metric.Confidence[0] > constraint.Limit
+			} else {
+				check.Met = metric.Confidence[0] >= constraint.Limit
+				check.Conclusive = check.Met || metric.Confidence[1] < constraint.Limit
+			}
+		}
+		candidate.Feasible = candidate.Feasible && check.Met
+		candidate.Constraints = append(candidate.Constraints, check)
+	}
+	// one constraint that is surely missed decides the candidate
+	candidate.Conclusive = candidate.Feasible
+	for _, check := range candidate.Constraints {
+		if check.Conclusive && !check.Met {
+			candidate.Conclusive = true
+		}
+	}
+	if optimize.CostMetric != "" {
+		candidate.Cost = FindMetric(summary, optimize.CostMetric)
+	}
+	return candidate
+}
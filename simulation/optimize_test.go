@@ -0,0 +1,105 @@
+package simulation
+
+import (
+	"des/models"
+	"strconv"
+	"testing"
+)
+
+func TestJudgeCandidate(t *testing.T) {
+	summary := &models.ReplicationSummary{
+		Replications: 10,
+		Metrics: []*models.MetricSummary{
+			{Name: "a", Mean: 1.25, Confidence: [2]float64{1, 1.5}},
+			{Name: "b", Mean: 2, Confidence: [2]float64{1.5, 2.5}},
+			{Name: "c", Mean: 2.75, Confidence: [2]float64{2.5, 3}},
+		},
+	}
+	tests := []struct {
+		name                 string
+		constraints          []models.OptimizeConstraint
+		feasible, conclusive bool
+	}{
+		{"max met", []models.OptimizeConstraint{{Metric: "a", Limit: 2}}, true, true},
+		{"max undecided", []models.OptimizeConstraint{{Metric: "b", Type: constraintMax, Limit: 2}}, false, false},
+		{"max missed", []models.OptimizeConstraint{{Metric: "c", Limit: 2}}, false, true},
+		{"min met", []models.OptimizeConstraint{{Metric: "c", Type: constraintMin, Limit: 2}}, true, true},
+		{"min undecided", []models.OptimizeConstraint{{Metric: "b", Type: constraintMin, Limit: 2}}, false, false},
+		{"min missed", []models.OptimizeConstraint{{Metric: "a", Type: constraintMin, Limit: 2}}, false, true},
+		{"all met", []models.OptimizeConstraint{{Metric: "a", Limit: 2}, {Metric: "c", Type: constraintMin, Limit: 2}}, true, true},
+		{"met and undecided", []models.OptimizeConstraint{{Metric: "a", Limit: 2}, {Metric: "b", Limit: 2}}, false, false},
+		{"one missed decides", []models.OptimizeConstraint{{Metric: "b", Limit: 2}, {Metric: "c", Limit: 2}}, false, true},
+		{"missing metric", []models.OptimizeConstraint{{Metric: "d", Limit: 2}}, false, false},
+	}
+	for _, tt := range tests {
+		candidate := judgeCandidate(summary, models.OptimizeConfig{Constraints: tt.constraints}, "1")
+		if candidate.Feasible != tt.feasible || candidate.Conclusive != tt.conclusive {
+			t.Errorf("%s: feasible %v, conclusive %v; want %v, %v",
+				tt.name, candidate.Feasible, candidate.Conclusive, tt.feasible, tt.conclusive)
+		}
+	}
+}
+
+func TestOptimizeFindsSmallestFeasibleServers(t *testing.T) {
+	// M/M/c with lambda = 2.5, mu = 1: utilization 2.5/c is 0.83 at c = 3
+	// and 0.625 at c = 4, so c = 4 is the fewest servers keeping it below 0.7
+	config := mm1TestConfig(2.5, 1, 500)
+	config.Optimize = models.OptimizeConfig{
+		Field:       "servers",
+		Constraints: []models.OptimizeConstraint{{Metric: "server_utilization", Limit: 0.7}},
+	}
+	if err := ValidateOptimize(config); err != nil {
+		t.Fatal(err)
+	}
+	points := serverCandidates(config, 18, 3, 11, 7, 4, 15, 5, 9, 6, 16, 8, 10, 12, 13, 14, 17)
+	result, err := Optimize(config, points, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Best == nil || result.Best.Value != "4" || !result.Best.Conclusive {
+		t.Fatalf("best candidate %+v, want 4 servers", result.Best)
+	}
+	// the binary search over 3..18 evaluates 18, 10, 6, 4 and 3
+	var evaluated []string
+	for _, candidate := range result.Candidates {
+		evaluated = append(evaluated, candidate.Value)
+		want := candidate.Value != "3"
+		if candidate.Feasible != want {
+			t.Errorf("%s servers judged feasible %v, want %v", candidate.Value, candidate.Feasible, want)
+		}
+	}
+	if got := len(evaluated); got != 5 {
+		t.Errorf("evaluated %v, want the 5 candidates of a binary search", evaluated)
+	}
+}
+
+func TestOptimizeWithoutFeasibleCandidate(t *testing.T) {
+	config := mm1TestConfig(2.5, 1, 500)
+	config.Optimize = models.OptimizeConfig{
+		Field:       "servers",
+		Constraints: []models.OptimizeConstraint{{Metric: "server_utilization", Limit: 0.1}},
+	}
+	result, err := Optimize(config, serverCandidates(config, 3, 4, 5, 6), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the largest candidate is infeasible, so nothing else is tried
+	if result.Best != nil || len(result.Candidates) != 1 || result.Candidates[0].Value != "6" {
+		t.Errorf("best %+v after %d candidates, want none after trying 6 servers only", result.Best, len(result.Candidates))
+	}
+}
+
+// serverCandidates returns one optimization candidate per server count, in
+// the given order.
+func serverCandidates(config *models.SimulationConfig, servers ...int) []*models.SweepPoint {
+	points := make([]*models.SweepPoint, len(servers))
+	for i, c := range servers {
+		pointConfig := *config
+		pointConfig.Servers = c
+		points[i] = &models.SweepPoint{
+			Settings: []models.SweepSetting{{Field: "servers", Value: strconv.Itoa(c)}},
+			Config:   &pointConfig,
+		}
+	}
+	return points
+}
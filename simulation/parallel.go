@@ -0,0 +1,63 @@
+package simulation
+
+import (
+	"des/models"
+	"runtime"
+	"sync"
+)
+
+// ProgressFunc is told how many of the total runs have completed. Calls are
+// never concurrent.
+type ProgressFunc func(done, total int)
+
+// Workers returns the number of runs executed concurrently: parallelism
+// when it is set, otherwise GOMAXPROCS.
+func Workers(config *models.SimulationConfig) int {
+	if config.Parallelism > 0 {
+		return config.Parallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// runParallel calls task(i) for every i in [0, n) on at most workers
+// goroutines and returns when all have finished. Tasks write their results
+// to index i, so the outcome does not depend on the scheduling.
+func runParallel(workers, n int, progress ProgressFunc, task func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			task(i)
+			if progress != nil {
+				progress(i+1, n)
+			}
+		}
+		return
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				task(i)
+				mu.Lock()
+				done++
+				if progress != nil {
+					progress(done, n)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
@@ -0,0 +1,102 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestRunParallelBoundsWorkers(t *testing.T) {
+	for _, workers := range []int{1, 3, 8} {
+		var mu sync.Mutex
+		active, peak := 0, 0
+		calls := make([]int, 50)
+		progressed := 0
+		runParallel(workers, len(calls), func(done, total int) { progressed = done }, func(i int) {
+			mu.Lock()
+			active++
+			peak = max(peak, active)
+			calls[i]++
+			mu.Unlock()
+			runtime.Gosched()
+			mu.Lock()
+			active--
+			mu.Unlock()
+		})
+		if peak > workers {
+			t.Errorf("%d workers: %d tasks ran at once", workers, peak)
+		}
+		for i, n := range calls {
+			if n != 1 {
+				t.Errorf("%d workers: task %d ran %d times", workers, i, n)
+			}
+		}
+		if progressed != len(calls) {
+			t.Errorf("%d workers: progress reached %d of %d", workers, progressed, len(calls))
+		}
+	}
+}
+
+// parallelTestConfig is a short M/M/1 run whose warm-up is detected from
+// pilot runs, so every run needs a pool of pilots of its own.
+func parallelTestConfig(parallelism int) *models.SimulationConfig {
+	return &models.SimulationConfig{
+		SimulationTime: 200,
+		ArrivalRate:    0.8,
+		ServiceRate:    1,
+		MaxQueueSize:   -1,
+		Servers:        1,
+		Parallelism:    parallelism,
+		StopCondition:  models.StopCondition{AutomaticMode: true, Type: "time"},
+		Random:         models.RandomConfig{Seed: 42, Distribution: "exponential"},
+		Warmup:         models.WarmupConfig{Method: "welch", PilotReplications: 4, WelchWindow: 5},
+	}
+}
+
+func TestSilentRunsDoNotNestPools(t *testing.T) {
+	// a run inside a pool detects its warm-up without a pool of its own
+	sim := newSilentSimulator(parallelTestConfig(8), 1, false)
+	if workers := Workers(sim.config); workers != 1 {
+		t.Errorf("a silent run starts its pilots on %d workers, want 1", workers)
+	}
+}
+
+func TestResultsIndependentOfParallelism(t *testing.T) {
+	replications := func(parallelism int) any {
+		return RunReplications(parallelTestConfig(parallelism), 6, nil)
+	}
+	sweep := func(parallelism int) any {
+		config := parallelTestConfig(parallelism)
+		var points []*models.SweepPoint
+		for _, rate := range []float64{0.5, 0.7, 0.9} {
+			pointConfig := *config
+			pointConfig.ArrivalRate = rate
+			points = append(points, &models.SweepPoint{
+				Settings: []models.SweepSetting{{Field: "arrival_rate", Value: fmt.Sprint(rate)}},
+				Config:   &pointConfig,
+			})
+		}
+		result := RunSweep(config, points, []string{"average_wait_time", "server_utilization"}, nil)
+		// the point configurations carry their parallelism, so compare the
+		// metrics, printed because single runs have NaN intervals
+		var metrics []string
+		for _, point := range result.Points {
+			for _, metric := range point.Metrics {
+				metrics = append(metrics, fmt.Sprintf("%+v", *metric))
+			}
+		}
+		return metrics
+	}
+
+	for name, run := range map[string]func(int) any{"replications": replications, "sweep": sweep} {
+		sequential := run(1)
+		for _, parallelism := range []int{2, 5} {
+			if got := run(parallelism); !reflect.DeepEqual(got, sequential) {
+				t.Errorf("%s with parallelism %d differs from the sequential run", name, parallelism)
+			}
+		}
+	}
+}
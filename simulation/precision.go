@@ -0,0 +1,171 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+)
+
+const (
+	stopPrecision          = "precision"
+	defaultMaxReplications = 100
+	defaultCheckInterval   = 100.0
+)
+
+// singleRunPrecisionMetrics are the metrics whose precision one run can judge
+// from its own per-customer series through batch means.
+var singleRunPrecisionMetrics = map[string]bool{
+	"average_wait_time":   true,
+	"average_system_time": true,
+}
+
+// ValidatePrecision checks that a precision stop condition names known
+// metrics and at least one target.
+func ValidatePrecision(config *models.SimulationConfig) error {
+	precision := config.StopCondition.Precision
+	if len(precision.Metrics) == 0 {
+		return fmt.Errorf("precision stop condition lists no metrics")
+	}
+	if precision.Relative <= 0 && precision.Absolute <= 0 {
+		return fmt.Errorf("precision stop condition needs a relative or absolute target")
+	}
+	for _, name := range precision.Metrics {
+		if _, err := LookupMetric(config, name); err != nil {
+			return err
+		}
+		if !singleRunPrecisionMetrics[name] && config.Replications <= 1 {
+			return fmt.Errorf("metric %q needs replications > 1 for a precision stop", name)
+		}
+	}
+	return nil
+}
+
+func newPrecisionReport(precision models.PrecisionConfig, budget string) *models.PrecisionReport {
+	return &models.PrecisionReport{
+		Relative:  precision.Relative,
+		Absolute:  precision.Absolute,
+		Converged: true,
+		Budget:    budget,
+	}
+}
+
+// addPrecisionResult records one metric's achieved half-width. A metric meets
+// its target if either the relative or the absolute target is satisfied.
+func addPrecisionResult(report *models.PrecisionReport, precision models.PrecisionConfig, name string, estimate, halfWidth float64) {
+	result := &models.PrecisionResult{
+		Metric:            name,
+		Estimate:          estimate,
+		HalfWidth:         halfWidth,
+		RelativeHalfWidth: math.Inf(1),
+	}
+	if estimate != 0 {
+		result.RelativeHalfWidth = halfWidth / math.Abs(estimate)
+	}
+	result.Met = (precision.Absolute > 0 && halfWidth <= precision.Absolute) ||
+		(precision.Relative > 0 && result.RelativeHalfWidth <= precision.Relative)
+
+	report.Metrics = append(report.Metrics, result)
+	report.Converged = report.Converged && result.Met
+}
+
+// PrecisionReport evaluates the current half-width of every precision metric
+// of a single run against the target.
+func (sc *EnhancedStatisticsCollector) PrecisionReport() *models.PrecisionReport {
+	precision := sc.config.StopCondition.Precision
+	report := newPrecisionReport(precision, fmt.Sprintf("simulation_time %.2f", sc.config.SimulationTime))
+	for _, name := range precision.Metrics {
+		moments, series, stream := sc.waitMoments, sc.waitTimes, sc.waitStream
+		if name == "average_system_time" {
+			moments, series, stream = sc.systemMoments, sc.systemTimes, sc.systemStream
+		}
+		halfWidth := math.Inf(1)
+		if stream != nil {
+			if result := stream.batchMeansInterval(confidenceLevel(sc.config)); result != nil {
+				halfWidth = result.HalfWidth
+			}
+		} else {
+			halfWidth = sc.meanHalfWidth(series)
+		}
+		addPrecisionResult(report, precision, name, moments.mean, halfWidth)
+	}
+	return report
+}
+
+// meanHalfWidth returns the confidence-interval half-width for the mean of
+// series using the configured output-analysis method, or +Inf if the series
+// is too short.
+func (sc *EnhancedStatisticsCollector) meanHalfWidth(series []float64) float64 {
+	level := confidenceLevel(sc.config)
+	if sc.config.OutputAnalysis.Method != methodIID {
+		if result := batchMeansInterval(series, sc.config.OutputAnalysis.Method, sc.config.OutputAnalysis.Batches, level); result != nil {
+			return result.HalfWidth
+		}
+		return math.Inf(1)
+	}
+	if len(series) < 2 {
+		return math.Inf(1)
+	}
+	return TCritical(level, len(series)-1) * math.Sqrt(sampleVariance(series)/float64(len(series)))
+}
+
+// RunReplicationsToPrecision adds replications one at a time, starting from
+// config.Replications, until every precision metric meets its target or
+// max_replications have been run. Each replication runs to simulation_time.
+//
+// Replications are computed ahead in batches of Workers(config) and used
+// one at a time; runs beyond the stopping point are discarded, so the result
+// does not depend on the number of workers.
+func RunReplicationsToPrecision(config *models.SimulationConfig, progress ProgressFunc) *models.ReplicationSummary {
+	precision := config.StopCondition.Precision
+	maxReplications := precision.MaxReplications
+	if maxReplications <= 0 {
+		maxReplications = defaultMaxReplications
+	}
+	minReplications := config.Replications
+	if minReplications < 2 {
+		minReplications = 2
+	}
+
+	runConfig := *ResolveWarmup(config)
+	runConfig.StopCondition.Type = stopTime
+	config = &runConfig
+	base := ResolveSeed(config.Random.Seed)
+
+	var seeds []int64
+	var runs, pending []*models.ComprehensiveMetrics
+	var summary *models.ReplicationSummary
+	for len(runs) < maxReplications {
+		if len(pending) == 0 {
+			batch := min(maxReplications-len(runs), max(Workers(config), minReplications-len(runs)))
+			batchSeeds := replicationSeeds(base, len(runs), batch)
+			pending = make([]*models.ComprehensiveMetrics, batch)
+			runParallel(Workers(config), batch, nil, func(i int) {
+				pending[i] = RunSilent(config, batchSeeds[i], false).Metrics
+			})
+		}
+		seeds = append(seeds, DeriveSeed(base, uint64(len(runs))))
+		runs = append(runs, pending[0])
+		pending = pending[1:]
+		if progress != nil {
+			progress(len(runs), maxReplications)
+		}
+		if len(runs) < minReplications {
+			continue
+		}
+
+		summary = summarizeReplications(config, seeds, runs)
+		summary.Precision = newPrecisionReport(precision, fmt.Sprintf("max_replications %d", maxReplications))
+		for _, name := range precision.Metrics {
+			estimate, halfWidth := math.NaN(), math.Inf(1)
+			if metric := FindMetric(summary, name); metric != nil {
+				estimate = metric.Mean
+				halfWidth = (metric.Confidence[1] - metric.Confidence[0]) / 2
+			}
+			addPrecisionResult(summary.Precision, precision, name, estimate, halfWidth)
+		}
+		if summary.Precision.Converged {
+			break
+		}
+	}
+	return summary
+}
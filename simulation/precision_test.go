@@ -0,0 +1,70 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func TestAddPrecisionResult(t *testing.T) {
+	tests := []struct {
+		name                string
+		relative, absolute  float64
+		estimate, halfWidth float64
+		wantRelative        float64
+		wantMet             bool
+	}{
+		{"relative met", 0.1, 0, 2, 0.2, 0.1, true},
+		{"relative missed", 0.1, 0, 2, 0.3, 0.15, false},
+		{"absolute met", 0, 0.25, 2, 0.25, 0.125, true},
+		{"either target suffices", 0.1, 0.5, 2, 0.4, 0.2, true},
+		{"both missed", 0.1, 0.25, 2, 0.3, 0.15, false},
+		{"zero estimate has no relative precision", 0.1, 0, 0, 0.01, math.Inf(1), false},
+		{"zero estimate can meet an absolute target", 0.1, 0.05, 0, 0.01, math.Inf(1), true},
+	}
+	for _, tt := range tests {
+		precision := models.PrecisionConfig{Relative: tt.relative, Absolute: tt.absolute}
+		report := newPrecisionReport(precision, "")
+		addPrecisionResult(report, precision, "m", tt.estimate, tt.halfWidth)
+		result := report.Metrics[0]
+		if got := result.RelativeHalfWidth; got != tt.wantRelative && math.Abs(got-tt.wantRelative) > 1e-12 {
+			t.Errorf("%s: relative half-width %g, want %g", tt.name, got, tt.wantRelative)
+		}
+		if result.Met != tt.wantMet || report.Converged != tt.wantMet {
+			t.Errorf("%s: met %v, converged %v, want %v", tt.name, result.Met, report.Converged, tt.wantMet)
+		}
+	}
+}
+
+func TestReplicationsStopAtFirstPrecise(t *testing.T) {
+	config := mm1TestConfig(0.5, 1, 500)
+	config.Replications = 3
+	config.StopCondition.Precision = models.PrecisionConfig{
+		Metrics:         []string{"average_wait_time"},
+		Relative:        0.05,
+		MaxReplications: 200,
+	}
+	summary := RunReplicationsToPrecision(config, nil)
+	n := summary.Replications
+	if !summary.Precision.Converged || n <= config.Replications || n >= 200 {
+		t.Fatalf("stopped after %d replications, converged %v", n, summary.Precision.Converged)
+	}
+
+	// recompute the relative half-width from the replication values: n values
+	// must meet the target and the first n-1 must not
+	values := FindMetric(summary, "average_wait_time").Values
+	def := MetricDefinition{Name: "average_wait_time"}
+	relative := func(m int) float64 {
+		s := summarizeMetric(def, values[:m], summary.ConfidenceLevel)
+		return (s.Confidence[1] - s.Confidence[0]) / 2 / math.Abs(s.Mean)
+	}
+	if relative(n) > 0.05 {
+		t.Errorf("%d replications give relative half-width %.4f > 0.05", n, relative(n))
+	}
+	if relative(n-1) <= 0.05 {
+		t.Errorf("%d replications already give relative half-width %.4f", n-1, relative(n-1))
+	}
+	if got := summary.Precision.Metrics[0].RelativeHalfWidth; math.Abs(got-relative(n)) > 1e-12 {
+		t.Errorf("reported relative half-width %g, recomputed %g", got, relative(n))
+	}
+}
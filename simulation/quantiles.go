@@ -0,0 +1,188 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	quantileOrderStatistics = "order_statistics"
+	quantileBootstrap       = "bootstrap"
+	defaultBootstrapSamples = 200
+)
+
+var defaultQuantileLevels = []float64{0.5, 0.75, 0.9, 0.95}
+
+// quantileLevels returns the configured quantile levels or the defaults.
+func quantileLevels(config *models.SimulationConfig) []float64 {
+	if len(config.Quantiles.Levels) == 0 {
+		return defaultQuantileLevels
+	}
+	return config.Quantiles.Levels
+}
+
+// ValidateQuantiles checks that every requested level lies in (0, 1) and that
+// the interval method is known.
+func ValidateQuantiles(config *models.SimulationConfig) error {
+	for _, p := range config.Quantiles.Levels {
+		if p <= 0 || p >= 1 {
+			return fmt.Errorf("quantile level %v must lie in (0, 1)", p)
+		}
+	}
+	switch config.Quantiles.CIMethod {
+	case "", quantileOrderStatistics, quantileBootstrap:
+		return nil
+	}
+	return fmt.Errorf("unknown quantile ci_method %q (valid: %s, %s)", config.Quantiles.CIMethod, quantileOrderStatistics, quantileBootstrap)
+}
+
+// quantileKey names a level the way the percentile maps do, e.g. "99.9th".
+func quantileKey(p float64) string {
+	return strconv.FormatFloat(p*100, 'f', -1, 64) + "th"
+}
+
+// quantileMetricSuffix names a level for metric names, e.g. "p99_9".
+func quantileMetricSuffix(p float64) string {
+	return "p" + strings.Replace(strconv.FormatFloat(p*100, 'f', -1, 64), ".", "_", 1)
+}
+
+// calculatePercentiles estimates every configured quantile of wait time,
+// system time and queue length together with its confidence interval.
+func (sc *EnhancedStatisticsCollector) calculatePercentiles() {
+	levels := quantileLevels(sc.config)
+	level := confidenceLevel(sc.config)
+
+	if sc.config.Streaming.Enabled {
+		sc.metrics.WaitTimePercentiles, sc.metrics.WaitTimeQuantiles = sc.waitStream.quantiles(levels, level)
+		sc.metrics.SystemTimePercentiles, sc.metrics.SystemTimeQuantiles = sc.systemStream.quantiles(levels, level)
+	} else {
+		sc.metrics.WaitTimePercentiles, sc.metrics.WaitTimeQuantiles = sc.sampleQuantiles(sc.waitTimes, levels, level)
+		sc.metrics.SystemTimePercentiles, sc.metrics.SystemTimeQuantiles = sc.sampleQuantiles(sc.systemTimes, levels, level)
+	}
+	sc.metrics.QueueLengthPercentiles, sc.metrics.QueueLengthQuantiles = sc.queueLengthQuantiles(levels, level)
+}
+
+func (sc *EnhancedStatisticsCollector) sampleQuantiles(series []float64, levels []float64, level float64) (map[string]float64, []*models.QuantileEstimate) {
+	percentiles := make(map[string]float64)
+	if len(series) == 0 {
+		return percentiles, nil
+	}
+	sorted := append([]float64{}, series...)
+	sort.Float64s(sorted)
+
+	var replicates [][]float64
+	if sc.config.Quantiles.CIMethod == quantileBootstrap {
+		replicates = sc.bootstrapQuantiles(series, levels)
+	}
+
+	estimates := make([]*models.QuantileEstimate, len(levels))
+	for i, p := range levels {
+		estimate := &models.QuantileEstimate{
+			Level:    p,
+			Key:      quantileKey(p),
+			Estimate: sc.calculatePercentile(sorted, p),
+		}
+		if replicates != nil {
+			estimate.Confidence = percentileInterval(replicates[i], level)
+		} else {
+			estimate.Confidence = orderStatisticInterval(sorted, p, level)
+		}
+		percentiles[estimate.Key] = estimate.Estimate
+		estimates[i] = estimate
+	}
+	return percentiles, estimates
+}
+
+// orderStatisticInterval returns the distribution-free interval
+// [X(l), X(u)] with ranks np -/+ z*sqrt(np(1-p)). It assumes independent
+// observations, so on autocorrelated series it is optimistic.
+func orderStatisticInterval(sorted []float64, p, level float64) [2]float64 {
+	n := float64(len(sorted))
+	z := NormalQuantile(1 - (1-level)/2)
+	spread := z * math.Sqrt(n*p*(1-p))
+	lower := int(math.Floor(n*p - spread))
+	upper := int(math.Ceil(n*p + spread))
+	if lower < 0 {
+		lower = 0
+	}
+	if upper > len(sorted)-1 {
+		upper = len(sorted) - 1
+	}
+	return [2]float64{sorted[lower], sorted[upper]}
+}
+
+// bootstrapQuantiles resamples series with the moving-block bootstrap, using
+// blocks of about n^(1/3) observations to keep the autocorrelation, and
+// returns the bootstrap replicates of each quantile.
+func (sc *EnhancedStatisticsCollector) bootstrapQuantiles(series []float64, levels []float64) [][]float64 {
+	samples := sc.config.Quantiles.BootstrapSamples
+	if samples <= 0 {
+		samples = defaultBootstrapSamples
+	}
+	n := len(series)
+	blockLength := int(math.Ceil(math.Cbrt(float64(n))))
+	rng := rand.New(rand.NewSource(DeriveSeed(sc.seed, bootstrapStream)))
+
+	replicates := make([][]float64, len(levels))
+	resample := make([]float64, 0, n+blockLength)
+	for b := 0; b < samples; b++ {
+		resample = resample[:0]
+		for len(resample) < n {
+			start := rng.Intn(n - blockLength + 1)
+			resample = append(resample, series[start:start+blockLength]...)
+		}
+		resample = resample[:n]
+		sort.Float64s(resample)
+		for i, p := range levels {
+			replicates[i] = append(replicates[i], sc.calculatePercentile(resample, p))
+		}
+	}
+	return replicates
+}
+
+// percentileInterval returns the bootstrap percentile interval.
+func percentileInterval(replicates []float64, level float64) [2]float64 {
+	sorted := append([]float64{}, replicates...)
+	sort.Float64s(sorted)
+	alpha := (1 - level) / 2
+	lower := int(math.Floor(alpha * float64(len(sorted)-1)))
+	upper := int(math.Ceil((1 - alpha) * float64(len(sorted)-1)))
+	return [2]float64{sorted[lower], sorted[upper]}
+}
+
+// queueLengthQuantiles reads the quantiles of the time-average queue-length
+// distribution from the time-weighted histogram. The interval is a t interval
+// over the same quantile computed in each complete section of the run.
+func (sc *EnhancedStatisticsCollector) queueLengthQuantiles(levels []float64, level float64) (map[string]float64, []*models.QuantileEstimate) {
+	percentiles := make(map[string]float64)
+	if sc.queueHistogram == nil || sc.queueHistogram.overall.total == 0 {
+		return percentiles, nil
+	}
+	sections := sc.queueHistogram.completeSections()
+
+	estimates := make([]*models.QuantileEstimate, len(levels))
+	for i, p := range levels {
+		estimate := &models.QuantileEstimate{
+			Level:    p,
+			Key:      quantileKey(p),
+			Estimate: sc.queueHistogram.overall.quantile(p),
+		}
+		estimate.Confidence = [2]float64{estimate.Estimate, estimate.Estimate}
+		if len(sections) >= 2 {
+			values := make([]float64, len(sections))
+			for j, section := range sections {
+				values[j] = section.quantile(p)
+			}
+			halfWidth := TCritical(level, len(values)-1) * math.Sqrt(sampleVariance(values)/float64(len(values)))
+			estimate.Confidence = [2]float64{estimate.Estimate - halfWidth, estimate.Estimate + halfWidth}
+		}
+		percentiles[estimate.Key] = estimate.Estimate
+		estimates[i] = estimate
+	}
+	return percentiles, estimates
+}
@@ -0,0 +1,185 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"sort"
+)
+
+const regenerativeHistogramBins = 10
+
+// regenerationCycle accumulates one cycle between consecutive arrivals to an
+// empty system. Every customer admitted in a cycle also leaves in it.
+type regenerationCycle struct {
+	length    float64
+	customers float64
+	arrivals  float64
+	rejected  float64
+	areaQ     float64
+	areaBusy  float64
+	waitSum   float64
+	systemSum float64
+}
+
+// regenerativeRatios are the ratio estimators formed over the cycles.
+var regenerativeRatios = []struct {
+	metric      string
+	numerator   func(c regenerationCycle, servers float64) float64
+	denominator func(c regenerationCycle, servers float64) float64
+}{
+	{"average_wait_time", func(c regenerationCycle, _ float64) float64 { return c.waitSum }, func(c regenerationCycle, _ float64) float64 { return c.customers }},
+	{"average_system_time", func(c regenerationCycle, _ float64) float64 { return c.systemSum }, func(c regenerationCycle, _ float64) float64 { return c.customers }},
+	{"average_queue_length", func(c regenerationCycle, _ float64) float64 { return c.areaQ }, func(c regenerationCycle, _ float64) float64 { return c.length }},
+	{"average_in_system", func(c regenerationCycle, _ float64) float64 { return c.areaQ + c.areaBusy }, func(c regenerationCycle, _ float64) float64 { return c.length }},
+	{"server_utilization", func(c regenerationCycle, _ float64) float64 { return c.areaBusy }, func(c regenerationCycle, servers float64) float64 { return c.length * servers }},
+	{"blocking_probability", func(c regenerationCycle, _ float64) float64 { return c.rejected }, func(c regenerationCycle, _ float64) float64 { return c.arrivals }},
+}
+
+// regenerationAccumulator folds every completed cycle into running sums, so
+// its memory does not grow with the number of cycles. Only the cycle length
+// percentiles and histogram need the lengths themselves: they are stored
+// unless streaming is enabled, in which case a sketch gives the percentiles
+// and the histogram is left out.
+type regenerationAccumulator struct {
+	servers   float64
+	cycles    int
+	lengths   welford
+	min       float64
+	max       float64
+	stored    []float64
+	sketch    *ddSketch
+	customers float64
+	ratios    []ratioSums
+}
+
+func newRegenerationAccumulator(config *models.SimulationConfig) *regenerationAccumulator {
+	ra := &regenerationAccumulator{
+		servers: float64(serverCount(config)),
+		min:     math.Inf(1),
+		max:     math.Inf(-1),
+		ratios:  make([]ratioSums, len(regenerativeRatios)),
+	}
+	if config.Streaming.Enabled {
+		ra.sketch = newDDSketch(config.Streaming.RelativeAccuracy)
+	}
+	return ra
+}
+
+func (ra *regenerationAccumulator) add(cycle regenerationCycle) {
+	ra.cycles++
+	ra.lengths.add(cycle.length)
+	ra.min = math.Min(ra.min, cycle.length)
+	ra.max = math.Max(ra.max, cycle.length)
+	if ra.sketch != nil {
+		ra.sketch.add(cycle.length)
+	} else {
+		ra.stored = append(ra.stored, cycle.length)
+	}
+	ra.customers += cycle.customers
+	for i, ratio := range regenerativeRatios {
+		ra.ratios[i].add(ratio.numerator(cycle, ra.servers), ratio.denominator(cycle, ra.servers))
+	}
+}
+
+// StartCycle marks a regeneration point: an arrival finding the queue empty
+// and the server idle. It closes the cycle in progress, if any.
+func (sc *EnhancedStatisticsCollector) StartCycle(t float64) {
+	if !sc.config.Regenerative.Enabled {
+		return
+	}
+	if sc.cycleActive {
+		sc.currentCycle.length = t - sc.cycleStart
+		sc.regeneration.add(sc.currentCycle)
+	}
+	sc.currentCycle = regenerationCycle{}
+	sc.cycleStart = t
+	sc.cycleActive = true
+}
+
+// calculateRegenerative forms the classical ratio estimators sum(Y)/sum(X)
+// over complete cycles with half-width t * s / (mean(X) * sqrt(n)), where
+// s^2 = s_YY - 2r s_XY + r^2 s_XX.
+func (sc *EnhancedStatisticsCollector) calculateRegenerative() {
+	sc.metrics.Regenerative = nil
+	ra := sc.regeneration
+	if !sc.config.Regenerative.Enabled || ra.cycles < 2 {
+		return
+	}
+
+	n := ra.cycles
+	analysis := &models.RegenerativeAnalysis{
+		Cycles:            n,
+		CycleLengthMean:   ra.lengths.mean,
+		CycleLengthStdDev: math.Sqrt(ra.lengths.m2 / float64(n-1)),
+		CycleLengthMin:    ra.min,
+		CycleLengthMax:    ra.max,
+		CustomersPerCycle: ra.customers / float64(n),
+	}
+	if ra.sketch != nil {
+		analysis.CycleLengthPercentiles = map[string]float64{
+			"50th": ra.sketch.quantile(0.5),
+			"90th": ra.sketch.quantile(0.9),
+			"99th": ra.sketch.quantile(0.99),
+		}
+	} else {
+		sorted := append([]float64{}, ra.stored...)
+		sort.Float64s(sorted)
+		analysis.CycleLengthPercentiles = map[string]float64{
+			"50th": sc.calculatePercentile(sorted, 0.5),
+			"90th": sc.calculatePercentile(sorted, 0.9),
+			"99th": sc.calculatePercentile(sorted, 0.99),
+		}
+		analysis.CycleLengthHistogram = buildHistogram(ra.stored, regenerativeHistogramBins)
+	}
+
+	level := confidenceLevel(sc.config)
+	for i, ratio := range regenerativeRatios {
+		analysis.Estimates = append(analysis.Estimates, ra.ratios[i].estimate(ratio.metric, level))
+	}
+	sc.metrics.Regenerative = analysis
+}
+
+// ratioSums accumulates the sums a ratio estimator needs over pairs (y, x):
+// the means and the sums of squares and cross-products, kept as centred
+// co-moments so that long runs do not lose precision.
+type ratioSums struct {
+	n     int
+	yMean float64
+	xMean float64
+	syy   float64
+	sxx   float64
+	sxy   float64
+}
+
+func (rs *ratioSums) add(y, x float64) {
+	rs.n++
+	dy, dx := y-rs.yMean, x-rs.xMean
+	rs.yMean += dy / float64(rs.n)
+	rs.xMean += dx / float64(rs.n)
+	rs.syy += dy * (y - rs.yMean)
+	rs.sxx += dx * (x - rs.xMean)
+	rs.sxy += dx * (y - rs.yMean)
+}
+
+func (rs *ratioSums) estimate(metric string, level float64) *models.RatioEstimate {
+	estimate := &models.RatioEstimate{Metric: metric}
+	if rs.xMean == 0 {
+		return estimate
+	}
+	n := rs.n
+	r := rs.yMean / rs.xMean
+	s2 := (rs.syy - 2*r*rs.sxy + r*r*rs.sxx) / float64(n-1)
+	halfWidth := TCritical(level, n-1) * math.Sqrt(math.Max(s2, 0)) / (rs.xMean * math.Sqrt(float64(n)))
+
+	estimate.Estimate = r
+	estimate.Confidence = [2]float64{r - halfWidth, r + halfWidth}
+	return estimate
+}
+
+func ratioEstimate(metric string, y, x []float64, level float64) *models.RatioEstimate {
+	var sums ratioSums
+	for i := range y {
+		sums.add(y[i], x[i])
+	}
+	return sums.estimate(metric, level)
+}
@@ -0,0 +1,65 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func TestRatioSumsMatchTwoPass(t *testing.T) {
+	y := []float64{3.1, 0.4, 7.7, 2.2, 5.0, 1.3, 9.8, 0.0}
+	x := []float64{2.0, 1.0, 4.5, 1.5, 3.0, 1.0, 5.5, 0.5}
+
+	n := float64(len(y))
+	yMean, xMean := sampleMean(y), sampleMean(x)
+	r := yMean / xMean
+	s2 := 0.0
+	for i := range y {
+		d := (y[i] - yMean) - r*(x[i]-xMean)
+		s2 += d * d
+	}
+	s2 /= n - 1
+	halfWidth := TCritical(0.95, len(y)-1) * math.Sqrt(s2) / (xMean * math.Sqrt(n))
+
+	got := ratioEstimate("test", y, x, 0.95)
+	if math.Abs(got.Estimate-r) > 1e-12 {
+		t.Errorf("estimate %g, want %g", got.Estimate, r)
+	}
+	want := [2]float64{r - halfWidth, r + halfWidth}
+	for i := range want {
+		if math.Abs(got.Confidence[i]-want[i]) > 1e-12 {
+			t.Errorf("interval %v, want %v", got.Confidence, want)
+		}
+	}
+}
+
+func TestRegenerationAccumulatorStreamingStoresNoCycles(t *testing.T) {
+	tests := []struct {
+		name      string
+		streaming bool
+	}{
+		{"stored", false},
+		{"streaming", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &models.SimulationConfig{Streaming: models.StreamingConfig{Enabled: tt.streaming}}
+			ra := newRegenerationAccumulator(config)
+			for i := 0; i < 1000; i++ {
+				ra.add(regenerationCycle{length: float64(i%7 + 1), customers: 2, waitSum: 1})
+			}
+			if ra.cycles != 1000 {
+				t.Fatalf("%d cycles, want 1000", ra.cycles)
+			}
+			if tt.streaming && ra.stored != nil {
+				t.Errorf("streaming stored %d cycle lengths", len(ra.stored))
+			}
+			if !tt.streaming && len(ra.stored) != 1000 {
+				t.Errorf("stored %d cycle lengths, want 1000", len(ra.stored))
+			}
+			if wait := ra.ratios[0].estimate("average_wait_time", 0.95); math.Abs(wait.Estimate-0.5) > 1e-12 {
+				t.Errorf("wait ratio %g, want 0.5", wait.Estimate)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+)
+
+// RunReplications runs config n times with independent seeds derived from
+// random.seed and aggregates every scalar metric into a mean, standard
+// deviation and Student-t confidence interval. The replications run on
+// Workers(config) goroutines.
+func RunReplications(config *models.SimulationConfig, n int, progress ProgressFunc) *models.ReplicationSummary {
+	config = ResolveWarmup(config)
+	seeds := replicationSeeds(ResolveSeed(config.Random.Seed), 0, n)
+	runs := make([]*models.ComprehensiveMetrics, n)
+	runParallel(Workers(config), n, progress, func(i int) {
+		runs[i] = RunSilent(config, seeds[i], false).Metrics
+	})
+	return summarizeReplications(config, seeds, runs)
+}
+
+// replicationSeeds returns the seeds of replications first to first+n-1.
+func replicationSeeds(base int64, first, n int) []int64 {
+	seeds := make([]int64, n)
+	for i := range seeds {
+		seeds[i] = DeriveSeed(base, uint64(first+i))
+	}
+	return seeds
+}
+
+func summarizeReplications(config *models.SimulationConfig, seeds []int64, runs []*models.ComprehensiveMetrics) *models.ReplicationSummary {
+	summary := &models.ReplicationSummary{
+		Replications:    len(runs),
+		ConfidenceLevel: confidenceLevel(config),
+		Seeds:           seeds,
+	}
+	for _, def := range MetricDefinitions(config) {
+		values := make([]float64, len(runs))
+		for i, metrics := range runs {
+			values[i] = def.Value(metrics)
+		}
+		if metric := summarizeMetric(def, values, summary.ConfidenceLevel); metric != nil {
+			summary.Metrics = append(summary.Metrics, metric)
+		}
+	}
+	summary.SLA = replicationSLA(config, summary)
+	if config.Analytic.Enabled {
+		summary.Theory = CompareReplicationsWithTheory(config, summary)
+	}
+	for i, metrics := range runs {
+		for _, warning := range ConsistencyWarnings(metrics) {
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf("replication %d: %s", i+1, warning))
+		}
+	}
+	return summary
+}
+
+// summarizeMetric aggregates the replication values of one metric, ignoring
+// replications that did not produce it. It returns nil if none did.
+func summarizeMetric(def MetricDefinition, values []float64, level float64) *models.MetricSummary {
+	var valid []float64
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			valid = append(valid, v)
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	return &models.MetricSummary{
+		Name:       def.Name,
+		Label:      def.Label,
+		Values:     values,
+		Mean:       sampleMean(valid),
+		StdDev:     math.Sqrt(sampleVariance(valid)),
+		Confidence: tConfidenceInterval(valid, level),
+	}
+}
+
+// FindMetric returns the named metric of a summary, or nil.
+func FindMetric(summary *models.ReplicationSummary, name string) *models.MetricSummary {
+	for _, metric := range summary.Metrics {
+		if metric.Name == name {
+			return metric
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+// mm1TestConfig is an M/M/1 queue with unlimited waiting room, run for
+// simulationTime time units from a fixed seed.
+func mm1TestConfig(lambda, mu, simulationTime float64) *models.SimulationConfig {
+	return &models.SimulationConfig{
+		SimulationTime: simulationTime,
+		ArrivalRate:    lambda,
+		ServiceRate:    mu,
+		MaxQueueSize:   -1,
+		Servers:        1,
+		StopCondition:  models.StopCondition{AutomaticMode: true, Type: stopTime},
+		Random:         models.RandomConfig{Seed: 1, Distribution: "exponential"},
+	}
+}
+
+func TestSummarizeMetric(t *testing.T) {
+	def := MetricDefinition{Name: "m", Label: "M"}
+	values := []float64{1, 2, 3, 4, math.NaN()}
+	summary := summarizeMetric(def, values, 0.95)
+	// the NaN replication is ignored: mean 2.5, s^2 = 5/3, t_{0.975,3} = 3.182446
+	halfWidth := 3.182446 * math.Sqrt(5.0/3/4)
+	if summary.Mean != 2.5 || math.Abs(summary.StdDev-math.Sqrt(5.0/3)) > 1e-12 {
+		t.Errorf("mean %g, std dev %g, want 2.5 and %g", summary.Mean, summary.StdDev, math.Sqrt(5.0/3))
+	}
+	if math.Abs(summary.Confidence[0]-(2.5-halfWidth)) > 1e-5 || math.Abs(summary.Confidence[1]-(2.5+halfWidth)) > 1e-5 {
+		t.Errorf("interval %v, want 2.5 +- %g", summary.Confidence, halfWidth)
+	}
+	if len(summary.Values) != len(values) {
+		t.Errorf("%d values kept, want all %d", len(summary.Values), len(values))
+	}
+	if summarizeMetric(def, []float64{math.NaN(), math.NaN()}, 0.95) != nil {
+		t.Error("summary of replications that all lack the metric")
+	}
+}
+
+func TestReplicationsCoverTheory(t *testing.T) {
+	// M/M/1 with rho = 0.5: utilization 0.5, Wq = rho / (mu - lambda) = 1
+	config := mm1TestConfig(0.5, 1, 5000)
+	config.Warmup.Customers = 200
+	summary := RunReplications(config, 20, nil)
+
+	if summary.Replications != 20 {
+		t.Fatalf("%d replications, want 20", summary.Replications)
+	}
+	seeds := replicationSeeds(config.Random.Seed, 0, 20)
+	for i, seed := range summary.Seeds {
+		if seed != seeds[i] {
+			t.Fatalf("replication %d has seed %d, want %d", i, seed, seeds[i])
+		}
+	}
+	for _, theory := range []struct {
+		metric string
+		value  float64
+	}{
+		{"server_utilization", 0.5},
+		{"average_wait_time", 1},
+		{"average_system_time", 2},
+	} {
+		metric := FindMetric(summary, theory.metric)
+		if metric == nil {
+			t.Fatalf("no %s", theory.metric)
+		}
+		if theory.value < metric.Confidence[0] || theory.value > metric.Confidence[1] {
+			t.Errorf("%s interval [%.4f, %.4f] misses %g", theory.metric, metric.Confidence[0], metric.Confidence[1], theory.value)
+		}
+		if width := metric.Confidence[1] - metric.Confidence[0]; width > 0.2*theory.value {
+			t.Errorf("%s interval [%.4f, %.4f] is too wide to be a check", theory.metric, metric.Confidence[0], metric.Confidence[1])
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package simulation
+
+import "des/models"
+
+// RunSilent executes one complete run of config with the given seed, without
+// terminal rendering, and returns its results. The caller's config is not
+// modified.
+func RunSilent(config *models.SimulationConfig, seed int64, antithetic bool) *models.SimulationResults {
+	return newSilentSimulator(config, seed, antithetic).Run()
+}
+
+func newSilentSimulator(config *models.SimulationConfig, seed int64, antithetic bool) *DiscreteEventSimulator {
+	runConfig := *config
+	runConfig.Random.Seed = seed
+	runConfig.Random.Antithetic = antithetic
+	runConfig.Visualization.Enabled = false
+	runConfig.Departures.ExportFile = ""
+	runConfig.TimeSeries.ExportFile = ""
+	// a silent run is one task of a worker pool, so the warm-up pilots it
+	// may need run within that task instead of on a nested pool
+	runConfig.Parallelism = 1
+
+	sim := NewSimulator(&runConfig)
+	sim.Initialize()
+	return sim
+}
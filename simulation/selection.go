@@ -0,0 +1,237 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+)
+
+const (
+	selectionMin = "min"
+	selectionMax = "max"
+
+	defaultSelectionProbability  = 0.95
+	defaultSelectionInitial      = 10
+	defaultSelectionReplications = 1000
+)
+
+// ValidateSelection checks the metric, goal and parameters of a
+// ranking-and-selection run.
+func ValidateSelection(config *models.SimulationConfig) error {
+	selection := config.Selection
+	if _, err := LookupMetric(config, selection.Metric); err != nil {
+		return err
+	}
+	switch selection.Goal {
+	case "", selectionMin, selectionMax:
+	default:
+		return fmt.Errorf("selection goal must be %s or %s, got %q", selectionMin, selectionMax, selection.Goal)
+	}
+	if selection.Indifference <= 0 {
+		return fmt.Errorf("selection needs a positive indifference zone")
+	}
+	if selection.Probability < 0 || selection.Probability >= 1 {
+		return fmt.Errorf("probability of correct selection must be below 1")
+	}
+	if selection.InitialReplications != 0 && selection.InitialReplications < 2 {
+		return fmt.Errorf("selection needs at least 2 initial replications")
+	}
+	if selection.MaxReplications < 0 {
+		return fmt.Errorf("max_replications must be non-negative")
+	}
+	return nil
+}
+
+// SelectBest runs the Kim-Nelson fully sequential procedure on the systems
+// and returns the one with the best mean of the selection metric. When the
+// best mean exceeds every other by at least the indifference zone, it is
+// picked with at least the configured probability.
+//
+// Every system first gets initial_replications runs, from which the variance
+// of each pairwise difference is estimated. The procedure then adds one
+// replication to every surviving system at a time and eliminates a system
+// once its mean trails another's by more than a margin that shrinks as the
+// replications grow. Systems share seeds when common_random_numbers is set,
+// which makes the differences less variable and the elimination faster. If
+// several systems survive max_replications, the best mean among them is
+// picked and the result is marked as truncated.
+//
+// The selection settings are those of the first configuration. progress, if
+// not nil, is told the replications per surviving system after every stage.
+func SelectBest(configs []*models.SimulationConfig, names []string, progress ProgressFunc) (*models.SelectionResult, error) {
+	options := configs[0].Selection
+	k := len(configs)
+	if k < 2 {
+		return nil, fmt.Errorf("selection needs at least 2 systems, got %d", k)
+	}
+	n0 := options.InitialReplications
+	if n0 == 0 {
+		n0 = defaultSelectionInitial
+	}
+	budget := options.MaxReplications
+	if budget == 0 {
+		budget = defaultSelectionReplications
+	}
+	if budget < n0 {
+		return nil, fmt.Errorf("max_replications (%d) is below initial_replications (%d)", budget, n0)
+	}
+	probability := options.Probability
+	if probability == 0 {
+		probability = defaultSelectionProbability
+	}
+	if probability <= 1/float64(k) {
+		return nil, fmt.Errorf("probability of correct selection must exceed 1/%d", k)
+	}
+
+	metrics := make([]MetricDefinition, k)
+	resolved := make([]*models.SimulationConfig, k)
+	for i, config := range configs {
+		metric, err := LookupMetric(config, options.Metric)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", names[i], err)
+		}
+		metrics[i] = metric
+		resolved[i] = ResolveWarmup(config)
+	}
+	// the procedure maximises, so a minimised metric is negated
+	sign := 1.0
+	if options.Goal != selectionMax {
+		sign = -1
+	}
+	base := ResolveSeed(configs[0].Random.Seed)
+	seed := func(i, r int) int64 {
+		if options.CommonRandomNumbers {
+			return DeriveSeed(base, uint64(r))
+		}
+		return DeriveSeed(DeriveSeed(base, uint64(i)), uint64(r))
+	}
+
+	values := make([][]float64, k)
+	// replicate extends every listed system to n replications
+	replicate := func(systems []int, n int) error {
+		type selectionJob struct{ system, run int }
+		var jobs []selectionJob
+		for _, i := range systems {
+			for r := len(values[i]); r < n; r++ {
+				jobs = append(jobs, selectionJob{i, r})
+				values[i] = append(values[i], 0)
+			}
+		}
+		runParallel(Workers(configs[0]), len(jobs), nil, func(j int) {
+			job := jobs[j]
+			values[job.system][job.run] = sign * metrics[job.system].Value(RunSilent(resolved[job.system], seed(job.system, job.run), false).Metrics)
+		})
+		for _, job := range jobs {
+			if math.IsNaN(values[job.system][job.run]) {
+				return fmt.Errorf("%s is undefined in replication %d of %s", options.Metric, job.run+1, names[job.system])
+			}
+		}
+		return nil
+	}
+
+	result := &models.SelectionResult{
+		Metric:              options.Metric,
+		Goal:                options.Goal,
+		Indifference:        options.Indifference,
+		Probability:         probability,
+		InitialReplications: n0,
+		CommonRandomNumbers: options.CommonRandomNumbers,
+		Systems:             make([]*models.SelectedSystem, k),
+	}
+	if result.Goal == "" {
+		result.Goal = selectionMin
+	}
+	alive, eliminatedBy, err := kimNelson(values, n0, budget, probability, options.Indifference, replicate, progress)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Systems {
+		result.Systems[i] = &models.SelectedSystem{Name: names[i]}
+		if eliminatedBy[i] >= 0 {
+			result.Systems[i].Eliminated = true
+			result.Systems[i].EliminatedBy = names[eliminatedBy[i]]
+		}
+	}
+
+	best := alive[0]
+	for _, i := range alive {
+		if sampleMean(values[i]) > sampleMean(values[best]) {
+			best = i
+		}
+	}
+	result.Truncated = len(alive) > 1
+	result.Best = result.Systems[best]
+	for i, system := range result.Systems {
+		system.Replications = len(values[i])
+		system.Mean = sign * sampleMean(values[i])
+		system.StdDev = math.Sqrt(sampleVariance(values[i]))
+	}
+	return result, nil
+}
+
+// kimNelson runs the elimination of the Kim-Nelson procedure on systems
+// whose observations, larger being better, are values[i]; replicate extends
+// the listed systems to n observations each. It returns the surviving
+// systems and, for every system, the one that eliminated it (-1 if none).
+func kimNelson(values [][]float64, n0, budget int, probability, delta float64, replicate func(systems []int, n int) error, progress ProgressFunc) ([]int, []int, error) {
+	k := len(values)
+	alive := make([]int, k)
+	eliminatedBy := make([]int, k)
+	for i := range alive {
+		alive[i] = i
+		eliminatedBy[i] = -1
+	}
+	if err := replicate(alive, n0); err != nil {
+		return nil, nil, err
+	}
+
+	// h^2 from the Kim-Nelson constant eta, and the variance of every
+	// pairwise difference over the first n0 replications
+	alpha := 1 - probability
+	eta := 0.5 * (math.Pow(2*alpha/float64(k-1), -2/float64(n0-1)) - 1)
+	h2 := 2 * eta * float64(n0-1)
+	variances := make([][]float64, k)
+	for i := range variances {
+		variances[i] = make([]float64, k)
+		for l := range variances[i] {
+			differences := make([]float64, n0)
+			for r := range differences {
+				differences[r] = values[i][r] - values[l][r]
+			}
+			variances[i][l] = sampleVariance(differences)
+		}
+	}
+
+	for r := n0; ; r++ {
+		means := make([]float64, k)
+		for _, i := range alive {
+			means[i] = sampleMean(values[i])
+		}
+		var survivors []int
+		for _, i := range alive {
+			for _, l := range alive {
+				if l == i {
+					continue
+				}
+				margin := math.Max(0, delta/(2*float64(r))*(h2*variances[i][l]/(delta*delta)-float64(r)))
+				if means[i] < means[l]-margin {
+					eliminatedBy[i] = l
+					break
+				}
+			}
+			if eliminatedBy[i] < 0 {
+				survivors = append(survivors, i)
+			}
+		}
+		alive = survivors
+		if progress != nil {
+			progress(r, budget)
+		}
+		if len(alive) == 1 || r >= budget {
+			return alive, eliminatedBy, nil
+		}
+		if err := replicate(alive, r+1); err != nil {
+			return nil, nil, err
+		}
+	}
+}
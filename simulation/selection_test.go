@@ -0,0 +1,112 @@
+package simulation
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// normalSystems returns observations and a replicate function drawing
+// independent normal observations with the given means and unit variance.
+func normalSystems(means []float64, seed int64) ([][]float64, func([]int, int) error) {
+	rng := rand.New(rand.NewSource(seed))
+	values := make([][]float64, len(means))
+	replicate := func(systems []int, n int) error {
+		for _, i := range systems {
+			for len(values[i]) < n {
+				values[i] = append(values[i], means[i]+rng.NormFloat64())
+			}
+		}
+		return nil
+	}
+	return values, replicate
+}
+
+func TestKimNelsonSeparatedSystems(t *testing.T) {
+	values, replicate := normalSystems([]float64{0, 5, 10, 4}, 1)
+	alive, eliminatedBy, err := kimNelson(values, 10, 1000, 0.95, 1, replicate, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alive) != 1 || alive[0] != 2 {
+		t.Fatalf("survivors %v, want [2]", alive)
+	}
+	for i, by := range eliminatedBy {
+		if i == 2 {
+			if by != -1 {
+				t.Errorf("best system eliminated by %d", by)
+			}
+			continue
+		}
+		if by < 0 {
+			t.Errorf("system %d not eliminated", i)
+		}
+		// far behind the best, so eliminated by the first screening
+		if len(values[i]) != 10 {
+			t.Errorf("system %d got %d replications, want 10", i, len(values[i]))
+		}
+	}
+}
+
+func TestKimNelsonProbabilityOfCorrectSelection(t *testing.T) {
+	// slippage configuration: the best beats the rest by exactly the
+	// indifference zone, the least favourable case for the procedure
+	tests := []struct {
+		name        string
+		means       []float64
+		probability float64
+	}{
+		{"two systems", []float64{0, 0.5}, 0.9},
+		{"five systems", []float64{0, 0, 0.5, 0, 0}, 0.9},
+		{"five systems at 0.95", []float64{0, 0, 0, 0, 0.5}, 0.95},
+	}
+	const experiments = 200
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := 0
+			for i, mean := range tt.means {
+				if mean > tt.means[best] {
+					best = i
+				}
+			}
+			correct := 0
+			for e := 0; e < experiments; e++ {
+				values, replicate := normalSystems(tt.means, int64(e))
+				alive, _, err := kimNelson(values, 10, 100000, tt.probability, 0.5, replicate, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(alive) != 1 {
+					t.Fatalf("experiment %d: %d survivors without truncation", e, len(alive))
+				}
+				if alive[0] == best {
+					correct++
+				}
+			}
+			// allow for the sampling error of the estimate
+			if got := float64(correct) / experiments; got < tt.probability-0.04 {
+				t.Errorf("correct selection in %.3f of experiments, want at least %.2f", got, tt.probability)
+			}
+		})
+	}
+}
+
+func TestKimNelsonTruncates(t *testing.T) {
+	values, replicate := normalSystems([]float64{0, 0, 0}, 3)
+	var stages []int
+	progress := func(completed, total int) { stages = append(stages, completed) }
+	alive, _, err := kimNelson(values, 10, 20, 0.95, 0.01, replicate, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alive) < 2 {
+		t.Fatalf("survivors %v, expected truncation with several", alive)
+	}
+	for _, i := range alive {
+		if len(values[i]) != 20 {
+			t.Errorf("survivor %d has %d replications, want the budget 20", i, len(values[i]))
+		}
+	}
+	if len(stages) != 11 || stages[0] != 10 || stages[len(stages)-1] != 20 {
+		t.Errorf("progress reported stages %v, want 10 to 20", stages)
+	}
+}
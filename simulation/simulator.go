@@ -8,13 +8,22 @@ import (
 )
 
 type DiscreteEventSimulator struct {
-	state      *models.SystemState
-	config     *models.SimulationConfig
-	events     *EventManager
-	stats      *EnhancedStatisticsCollector
-	visualizer *TerminalVisualizer
-	customerID int
-	eventLog   []*models.EventLogEntry
+	state       *models.SystemState
+	config      *models.SimulationConfig
+	events      *EventManager
+	stats       *EnhancedStatisticsCollector
+	visualizer  *TerminalVisualizer
+	customerID  int
+	lastArrival float64
+	eventLog    []*models.EventLogEntry
+	warmup      *models.WarmupAnalysis
+	precision   *models.PrecisionReport
+	nextCheck   float64
+	stopPolicy  stopPolicy
+	stopReason  string
+	horizon     float64
+	startedAt   time.Time
+	departures  *departureExport
 }
 
 func (sim *DiscreteEventSimulator) GetState() *models.SystemState {
@@ -38,21 +47,21 @@ func (sim *DiscreteEventSimulator) GetEventLog() []*models.EventLogEntry {
 }
 
 func (sim *DiscreteEventSimulator) ProcessEvent(event *models.Event) {
-	timeDiff := event.Timestamp - sim.state.LastEventTime
-	if timeDiff > 0 {
-		sim.stats.UpdatePreEvent(sim.state, timeDiff)
-	}
-
-	sim.processEvent(event)
+	sim.advance(event)
 	sim.logEvent(event)
+}
+
+// advance moves the clock to the event, integrating statistics over the
+// elapsed interval, and applies the event.
+func (sim *DiscreteEventSimulator) advance(event *models.Event) {
+	sim.stats.AdvanceTo(sim.state, event.Timestamp)
 
 	sim.state.Clock = event.Timestamp
+	sim.processEvent(event)
+
 	sim.state.LastEventTime = sim.state.Clock
 	sim.state.EventsProcessed++
-}
-
-func (sim *DiscreteEventSimulator) ShouldStop() bool {
-	return sim.state.Clock >= sim.config.SimulationTime
+	sim.stats.CheckWarmup(sim.state)
 }
 
 func NewSimulator(config *models.SimulationConfig) *DiscreteEventSimulator {
@@ -68,12 +77,40 @@ func NewSimulator(config *models.SimulationConfig) *DiscreteEventSimulator {
 }
 
 func (sim *DiscreteEventSimulator) Initialize() {
+	if sim.config.Warmup.Method != "" && sim.warmup == nil {
+		sim.warmup = DetectWarmup(sim.config)
+	}
 	sim.initializeState()
+	sim.openDepartureExport()
 	firstArrivalTime := sim.events.GetInterarrivalTime()
-	sim.events.ScheduleEvent(models.EventArrival, firstArrivalTime, nil)
+	if !math.IsInf(firstArrivalTime, 1) {
+		sim.events.ScheduleEvent(models.EventArrival, firstArrivalTime, nil)
+	}
+}
+
+// openDepartureExport starts writing departure timestamps when
+// departures.export_file is set. A failure is logged and the run continues
+// without the export.
+func (sim *DiscreteEventSimulator) openDepartureExport() {
+	if sim.departures != nil {
+		sim.departures.close()
+		sim.departures = nil
+	}
+	if sim.config.Departures.ExportFile == "" {
+		return
+	}
+	export, err := newDepartureExport(sim.config.Departures.ExportFile)
+	if err != nil {
+		if sim.visualizer.logger != nil {
+			sim.visualizer.logger.LogError(err.Error())
+		}
+		return
+	}
+	sim.departures = export
 }
 
 func (sim *DiscreteEventSimulator) initializeState() {
+	sim.events.reset()
 	sim.state = &models.SystemState{
 		Clock:             0,
 		ServerBusy:        false,
@@ -89,8 +126,18 @@ func (sim *DiscreteEventSimulator) initializeState() {
 		EventsProcessed:   0,
 		RejectedCustomers: 0,
 	}
-	sim.stats = NewStatisticsCollector(sim.config)
+	sim.stats = NewStatisticsCollector(sim.config, sim.events.Seed())
+	if sim.warmup != nil {
+		sim.stats.SetWarmupCustomers(sim.warmup.TruncationCustomers)
+	}
 	sim.customerID = 1
+	sim.lastArrival = 0
+	sim.precision = nil
+	sim.nextCheck = sim.precisionCheckInterval()
+	sim.stopPolicy = newStopPolicy(sim.config)
+	sim.stopReason = ""
+	sim.horizon = math.Inf(1)
+	sim.startedAt = time.Now()
 	sim.eventLog = make([]*models.EventLogEntry, 0)
 }
 
@@ -100,28 +147,10 @@ func (sim *DiscreteEventSimulator) Run() *models.SimulationResults {
 		sim.visualizer.DisplayHeader(sim.config)
 	}
 	startTime := time.Now()
+	sim.startedAt = startTime
 
-	for {
-		nextEvent := sim.events.PeekNextEvent()
-		if nextEvent == nil {
-			break
-		}
-
-		if nextEvent.Timestamp > sim.config.SimulationTime {
-			break
-		}
-
-		timeDiffForStats := nextEvent.Timestamp - sim.state.LastEventTime
-		if timeDiffForStats > 0 {
-			sim.stats.UpdatePreEvent(sim.state, timeDiffForStats)
-		}
-
-		event := sim.events.GetNextEvent()
-		sim.state.Clock = event.Timestamp
-		sim.processEvent(event)
-
-		sim.state.LastEventTime = sim.state.Clock
-		sim.state.EventsProcessed++
+	for !sim.ShouldStop() {
+		sim.advance(sim.events.GetNextEvent())
 
 		if sim.config.Visualization.Enabled {
 			sim.visualizer.ClearScreen()
@@ -132,23 +161,83 @@ func (sim *DiscreteEventSimulator) Run() *models.SimulationResults {
 		}
 	}
 
-	if sim.state.Clock < sim.config.SimulationTime {
-		finalTimeDiffForStats := sim.config.SimulationTime - sim.state.LastEventTime
-		if finalTimeDiffForStats > 0 {
-			sim.stats.UpdatePreEvent(sim.state, finalTimeDiffForStats)
-		}
-		sim.state.Clock = sim.config.SimulationTime
+	return sim.Finish(time.Since(startTime))
+}
+
+// Finish closes the time-weighted statistics at the end of the horizon and
+// returns the final results.
+func (sim *DiscreteEventSimulator) Finish(runtime time.Duration) *models.SimulationResults {
+	if sim.stopReason == stopTime && sim.state.Clock < sim.horizon {
+		sim.stats.AdvanceTo(sim.state, sim.horizon)
+		sim.state.Clock = sim.horizon
+		sim.state.LastEventTime = sim.state.Clock
 	}
 
 	metrics := sim.stats.CalculateFinalMetrics(sim.state)
+	if hasRule(sim.stopPolicy.rules, stopPrecision) {
+		sim.precision = sim.stats.PrecisionReport()
+	}
+	var theory *models.TheoryValidation
+	if sim.config.Analytic.Enabled {
+		theory = CompareWithTheory(sim.config, metrics)
+	}
+	if sim.departures != nil {
+		if err := sim.departures.close(); err != nil && sim.visualizer.logger != nil {
+			sim.visualizer.logger.LogError(err.Error())
+		} else if metrics.DepartureProcess != nil {
+			metrics.DepartureProcess.ExportFile = sim.departures.path
+		}
+		sim.departures = nil
+	}
+	series := sim.stats.TimeSeries(sim.state.Clock)
+	if series != nil && sim.config.TimeSeries.ExportFile != "" {
+		if err := writeTimeSeries(sim.config.TimeSeries.ExportFile, series); err != nil {
+			if sim.visualizer.logger != nil {
+				sim.visualizer.logger.LogError(err.Error())
+			}
+		} else {
+			series.ExportFile = sim.config.TimeSeries.ExportFile
+		}
+	}
+	warnings := ConsistencyWarnings(metrics)
+	if sim.visualizer.logger != nil {
+		for _, warning := range warnings {
+			sim.visualizer.logger.LogWarn(warning)
+		}
+	}
 
 	return &models.SimulationResults{
-		Config:   sim.config,
-		Metrics:  metrics,
-		State:    sim.state,
-		Runtime:  time.Since(startTime),
-		EventLog: sim.eventLog,
+		Config:     sim.config,
+		Metrics:    metrics,
+		State:      sim.state,
+		Runtime:    runtime,
+		EventLog:   sim.eventLog,
+		Warmup:     sim.warmup,
+		Precision:  sim.precision,
+		StopReason: sim.stopReason,
+		Theory:     theory,
+		TimeSeries: series,
+		Warnings:   warnings,
+	}
+}
+
+func (sim *DiscreteEventSimulator) precisionCheckInterval() float64 {
+	if sim.config.StopCondition.Precision.CheckInterval > 0 {
+		return sim.config.StopCondition.Precision.CheckInterval
 	}
+	return defaultCheckInterval
+}
+
+// precisionReached evaluates the precision stop condition every check
+// interval of simulated time.
+func (sim *DiscreteEventSimulator) precisionReached() bool {
+	if sim.state.Clock < sim.nextCheck {
+		return false
+	}
+	for sim.nextCheck <= sim.state.Clock {
+		sim.nextCheck += sim.precisionCheckInterval()
+	}
+	return sim.stats.PrecisionReport().Converged
 }
 
 func (sim *DiscreteEventSimulator) processEvent(event *models.Event) {
@@ -163,13 +252,18 @@ func (sim *DiscreteEventSimulator) processEvent(event *models.Event) {
 func (sim *DiscreteEventSimulator) processArrival() {
 	serviceTime := sim.events.GetServiceTime()
 	customer := &models.Customer{
-		ID:          sim.customerID,
-		ArrivalTime: sim.state.Clock,
-		ServiceTime: serviceTime,
-		Status:      models.CustomerWaiting,
+		ID:               sim.customerID,
+		ArrivalTime:      sim.state.Clock,
+		InterarrivalTime: sim.state.Clock - sim.lastArrival,
+		ServiceTime:      serviceTime,
+		Status:           models.CustomerWaiting,
 	}
 	sim.customerID++
+	sim.lastArrival = sim.state.Clock
 	sim.state.TotalCustomers++
+	if sim.state.BusyServers == 0 && len(sim.state.Queue) == 0 {
+		sim.stats.StartCycle(sim.state.Clock)
+	}
 
 	logMessage := fmt.Sprintf("Customer %d arrived at time %.2f, service time=%.2f",
 		customer.ID, sim.state.Clock, serviceTime)
@@ -177,22 +271,21 @@ func (sim *DiscreteEventSimulator) processArrival() {
 		sim.visualizer.logger.LogInfo(logMessage)
 	}
 
-	if !sim.state.ServerBusy {
-		sim.state.ServerBusy = true
-		customer.ServiceStart = sim.state.Clock
-		customer.Status = models.CustomerInService
-		departureTime := sim.state.Clock + customer.ServiceTime
-		sim.events.ScheduleEvent(models.EventDeparture, departureTime, customer)
-		sim.state.CustomersServed++
-	} else if len(sim.state.Queue) < sim.config.MaxQueueSize {
+	if sim.state.BusyServers < serverCount(sim.config) {
+		if sim.state.BusyServers == 0 {
+			sim.stats.StartBusyPeriod(sim.state.Clock)
+		}
+		sim.startService(customer)
+	} else if sim.config.MaxQueueSize < 0 || len(sim.state.Queue) < sim.config.MaxQueueSize {
 		sim.state.Queue = append(sim.state.Queue, customer)
 	} else {
 		customer.Status = models.CustomerRejected
 		sim.state.RejectedCustomers++
 	}
+	sim.stats.RecordArrival(customer)
 
 	nextArrivalTime := sim.state.Clock + sim.events.GetInterarrivalTime()
-	if nextArrivalTime <= sim.config.SimulationTime {
+	if !math.IsInf(nextArrivalTime, 1) && nextArrivalTime <= sim.stopPolicy.arrivalHorizon() {
 		sim.events.ScheduleEvent(models.EventArrival, nextArrivalTime, nil)
 	}
 }
@@ -201,6 +294,9 @@ func (sim *DiscreteEventSimulator) processDeparture(customer *models.Customer) {
 	if customer != nil {
 		customer.ExitTime = sim.state.Clock
 		sim.stats.RecordCustomerCompletion(customer)
+		if sim.departures != nil {
+			sim.departures.write(sim.state.Clock)
+		}
 		logMessage := fmt.Sprintf("Customer %d departed at time %.2f",
 			customer.ID, sim.state.Clock)
 		if sim.visualizer.logger != nil {
@@ -208,6 +304,8 @@ func (sim *DiscreteEventSimulator) processDeparture(customer *models.Customer) {
 		}
 	}
 
+	sim.state.BusyServers--
+	sim.state.ServerBusy = sim.state.BusyServers > 0
 	if len(sim.state.Queue) > 0 {
 		nextCustomer := sim.state.Queue[0]
 		sim.state.Queue = sim.state.Queue[1:]
@@ -217,16 +315,30 @@ func (sim *DiscreteEventSimulator) processDeparture(customer *models.Customer) {
 			delay = 0
 		}
 		sim.state.TotalDelay += delay
+		sim.startService(nextCustomer)
+	}
+	if sim.state.BusyServers == 0 {
+		sim.stats.EndBusyPeriod(sim.state.Clock)
+	}
+}
 
-		sim.state.ServerBusy = true
-		nextCustomer.ServiceStart = sim.state.Clock
-		nextCustomer.Status = models.CustomerInService
-		departureTime := sim.state.Clock + nextCustomer.ServiceTime
-		sim.events.ScheduleEvent(models.EventDeparture, departureTime, nextCustomer)
-		sim.state.CustomersServed++
-	} else {
-		sim.state.ServerBusy = false
+// startService puts customer on a free server and schedules its departure.
+func (sim *DiscreteEventSimulator) startService(customer *models.Customer) {
+	sim.state.BusyServers++
+	sim.state.ServerBusy = true
+	customer.ServiceStart = sim.state.Clock
+	customer.Status = models.CustomerInService
+	departureTime := sim.state.Clock + customer.ServiceTime
+	sim.events.ScheduleEvent(models.EventDeparture, departureTime, customer)
+	sim.state.CustomersServed++
+}
+
+// serverCount returns the number of parallel servers, at least one.
+func serverCount(config *models.SimulationConfig) int {
+	if config.Servers < 1 {
+		return 1
 	}
+	return config.Servers
 }
 
 func (sim *DiscreteEventSimulator) logEvent(event *models.Event) {
@@ -0,0 +1,33 @@
+package simulation
+
+import (
+	"des/models"
+	"testing"
+)
+
+func TestReinitializeReplaysTrace(t *testing.T) {
+	config := &models.SimulationConfig{
+		SimulationTime: 100,
+		ServiceRate:    2,
+		MaxQueueSize:   -1,
+		StopCondition:  models.StopCondition{AutomaticMode: true, Type: "time"},
+		Random: models.RandomConfig{
+			Seed:                1,
+			ArrivalDistribution: "trace",
+			ArrivalTimes:        []float64{1, 2, 4, 7, 11},
+		},
+	}
+	sim := newSilentSimulator(config, 1, false)
+	sim.Initialize()
+
+	if n := sim.GetEvents().GetEventCount(); n != 1 {
+		t.Fatalf("%d events scheduled after reinitializing, want the first arrival only", n)
+	}
+	if first := sim.GetEvents().PeekNextEvent(); first.Timestamp != 1 {
+		t.Fatalf("first arrival at %g, want 1", first.Timestamp)
+	}
+	results := sim.Run()
+	if results.State.TotalCustomers != 5 {
+		t.Errorf("%d customers arrived, want the 5 of the trace", results.State.TotalCustomers)
+	}
+}
@@ -0,0 +1,258 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	slaWithin  = "within"
+	slaExceeds = "exceeds"
+
+	slaWaitTime    = "wait_time"
+	slaSystemTime  = "system_time"
+	slaQueueLength = "queue_length"
+	slaInSystem    = "in_system"
+)
+
+var slaMetrics = []string{slaWaitTime, slaSystemTime, slaQueueLength, slaInSystem}
+
+// ValidateSLA checks the metric, type, threshold and target of every
+// service-level target, and that no two targets share a name or measure the
+// same fraction, which would make one shadow the other in metric lookups.
+func ValidateSLA(config *models.SimulationConfig) error {
+	names := make(map[string]int)
+	metrics := make(map[string]int)
+	for i, target := range config.SLA.Targets {
+		known := false
+		for _, metric := range slaMetrics {
+			known = known || target.Metric == metric
+		}
+		if !known {
+			return fmt.Errorf("sla target %d: unknown metric %q (valid: %s)", i+1, target.Metric, strings.Join(slaMetrics, ", "))
+		}
+		switch target.Type {
+		case "", slaWithin, slaExceeds:
+		default:
+			return fmt.Errorf("sla target %d: unknown type %q (valid: %s, %s)", i+1, target.Type, slaWithin, slaExceeds)
+		}
+		if target.Threshold < 0 {
+			return fmt.Errorf("sla target %d: threshold must be non-negative", i+1)
+		}
+		if target.Target < 0 || target.Target > 1 {
+			return fmt.Errorf("sla target %d: target %v must lie in [0, 1]", i+1, target.Target)
+		}
+		if j, ok := names[slaName(target)]; ok {
+			return fmt.Errorf("sla targets %d and %d are both named %q", j, i+1, slaName(target))
+		}
+		if j, ok := metrics[slaMetricName(target)]; ok {
+			return fmt.Errorf("sla targets %d and %d both measure %s", j, i+1, slaMetricName(target))
+		}
+		names[slaName(target)] = i + 1
+		metrics[slaMetricName(target)] = i + 1
+	}
+	return nil
+}
+
+func slaType(target models.SLATarget) string {
+	if target.Type == "" {
+		return slaWithin
+	}
+	return target.Type
+}
+
+// slaName returns the configured name or describes the target, e.g.
+// "80% wait_time <= 20" or "P(system_time > 2) <= 1%".
+func slaName(target models.SLATarget) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	if slaType(target) == slaExceeds {
+		return fmt.Sprintf("P(%s > %g) <= %g%%", target.Metric, target.Threshold, target.Target*100)
+	}
+	return fmt.Sprintf("%g%% %s <= %g", target.Target*100, target.Metric, target.Threshold)
+}
+
+// slaMetricName names a target for metric lookups, e.g. sla_wait_time_within_20.
+func slaMetricName(target models.SLATarget) string {
+	threshold := strings.Replace(strconv.FormatFloat(target.Threshold, 'f', -1, 64), ".", "_", 1)
+	return "sla_" + target.Metric + "_" + slaType(target) + "_" + threshold
+}
+
+func slaValue(results []*models.SLAResult, i int) float64 {
+	if i >= len(results) {
+		return math.NaN()
+	}
+	return results[i].Attained
+}
+
+// indicatorBatch counts the customers of one batch meeting a condition.
+type indicatorBatch struct {
+	hits  float64
+	count float64
+}
+
+// indicatorSeries counts the customers meeting a condition in at most
+// maxSections consecutive batches of customers, so the fraction and its
+// batch-means interval need constant memory.
+type indicatorSeries struct {
+	batches *timeSections[*indicatorBatch]
+}
+
+func newIndicatorSeries() *indicatorSeries {
+	return &indicatorSeries{batches: newTimeSections(
+		func() *indicatorBatch { return &indicatorBatch{} },
+		func(into, from *indicatorBatch) {
+			into.hits += from.hits
+			into.count += from.count
+		},
+	)}
+}
+
+func (is *indicatorSeries) add(hit bool) {
+	is.batches.advance(1, func(batch *indicatorBatch, _ float64) {
+		if hit {
+			batch.hits++
+		}
+		batch.count++
+	})
+}
+
+// fraction returns the overall fraction and the fraction in each complete
+// batch.
+func (is *indicatorSeries) fraction() (float64, []float64) {
+	hits, count := 0.0, 0.0
+	for _, batch := range is.batches.sections {
+		hits += batch.hits
+		count += batch.count
+	}
+	if count == 0 {
+		return math.NaN(), nil
+	}
+	var batches []float64
+	for _, batch := range is.batches.complete() {
+		batches = append(batches, batch.hits/batch.count)
+	}
+	return hits / count, batches
+}
+
+// fractionAtMost returns the fraction of time the state was at most threshold.
+func fractionAtMost(h *timeWeightedHistogram, threshold float64) float64 {
+	if h.total == 0 {
+		return math.NaN()
+	}
+	within := 0.0
+	for level, dt := range h.time {
+		if float64(level) <= threshold {
+			within += dt
+		}
+	}
+	return within / h.total
+}
+
+func (sc *EnhancedStatisticsCollector) resetSLA() {
+	sc.slaSeries = make([]*indicatorSeries, len(sc.config.SLA.Targets))
+	for i := range sc.slaSeries {
+		sc.slaSeries[i] = newIndicatorSeries()
+	}
+}
+
+// recordSLA counts a completed customer against the wait and system time
+// targets. A customer is within a target if its time does not exceed the
+// threshold.
+func (sc *EnhancedStatisticsCollector) recordSLA(waitTime, systemTime float64) {
+	for i, target := range sc.config.SLA.Targets {
+		switch target.Metric {
+		case slaWaitTime:
+			sc.slaSeries[i].add(waitTime <= target.Threshold)
+		case slaSystemTime:
+			sc.slaSeries[i].add(systemTime <= target.Threshold)
+		}
+	}
+}
+
+// calculateSLA estimates, for every target, the fraction of customers (wait
+// and system time) or of time (queue length and number in system) within
+// the threshold. The interval is a t interval over the batches or sections.
+// Exceedance targets report one minus that fraction.
+func (sc *EnhancedStatisticsCollector) calculateSLA() {
+	sc.metrics.SLA = nil
+	level := confidenceLevel(sc.config)
+	for i, target := range sc.config.SLA.Targets {
+		var within float64
+		var replicates []float64
+		switch target.Metric {
+		case slaWaitTime, slaSystemTime:
+			within, replicates = sc.slaSeries[i].fraction()
+		default:
+			histogram := sc.queueHistogram
+			if target.Metric == slaInSystem {
+				histogram = sc.systemHistogram
+			}
+			within = fractionAtMost(&histogram.overall, target.Threshold)
+			for _, section := range histogram.completeSections() {
+				replicates = append(replicates, fractionAtMost(section, target.Threshold))
+			}
+		}
+
+		result := &models.SLAResult{
+			Name:       slaName(target),
+			Metric:     target.Metric,
+			Type:       slaType(target),
+			Threshold:  target.Threshold,
+			Target:     target.Target,
+			Attained:   within,
+			Confidence: [2]float64{within, within},
+		}
+		if len(replicates) >= 2 {
+			halfWidth := TCritical(level, len(replicates)-1) * math.Sqrt(sampleVariance(replicates)/float64(len(replicates)))
+			result.Confidence = [2]float64{within - halfWidth, within + halfWidth}
+		}
+		if result.Type == slaExceeds {
+			result.Attained = 1 - within
+			result.Confidence = [2]float64{1 - result.Confidence[1], 1 - result.Confidence[0]}
+		}
+		judgeSLA(result)
+		sc.metrics.SLA = append(sc.metrics.SLA, result)
+	}
+}
+
+// judgeSLA decides pass or fail from the point estimate. The verdict is
+// conclusive when the interval lies entirely on one side of the target.
+func judgeSLA(result *models.SLAResult) {
+	if math.IsNaN(result.Attained) {
+		return
+	}
+	if result.Type == slaExceeds {
+		result.Passed = result.Attained <= result.Target
+	} else {
+		result.Passed = result.Attained >= result.Target
+	}
+	result.Conclusive = result.Confidence[0] > result.Target || result.Confidence[1] < result.Target
+}
+
+// replicationSLA judges every target on the replication mean and t interval
+// of its attained fraction.
+func replicationSLA(config *models.SimulationConfig, summary *models.ReplicationSummary) []*models.SLAResult {
+	var results []*models.SLAResult
+	for _, target := range config.SLA.Targets {
+		result := &models.SLAResult{
+			Name:      slaName(target),
+			Metric:    target.Metric,
+			Type:      slaType(target),
+			Threshold: target.Threshold,
+			Target:    target.Target,
+			Attained:  math.NaN(),
+		}
+		if metric := FindMetric(summary, slaMetricName(target)); metric != nil {
+			result.Attained = metric.Mean
+			result.Confidence = metric.Confidence
+		}
+		judgeSLA(result)
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,100 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func TestSLAAttainedFraction(t *testing.T) {
+	targets := []models.SLATarget{
+		{Metric: slaWaitTime, Type: slaWithin, Threshold: 1, Target: 0.7},
+		{Metric: slaSystemTime, Type: slaExceeds, Threshold: 1, Target: 0.2},
+	}
+	tests := []struct {
+		name      string
+		customers int
+		wait      func(i int) float64
+		within    float64
+		halfWidth float64
+	}{
+		// 20 batches of one customer: 15 within, so s^2 = 0.75 * 0.25 * 20/19
+		// and the half-width is t_{0.975,19} sqrt(s^2/20) = 2.093024 * 0.099340
+		{"one customer per batch", 20, func(i int) float64 {
+			if i >= 10 && i%2 == 1 {
+				return 2
+			}
+			return 0.5
+		}, 0.75, 0.207921},
+		// after 130 customers the first 128 form 32 complete batches of 4,
+		// each with 3 of 4 within, so the batch fractions do not vary
+		{"merged batches", 130, func(i int) float64 {
+			if i%4 == 3 {
+				return 2
+			}
+			return 0.5
+		}, 98.0 / 130, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &models.SimulationConfig{SLA: models.SLAConfig{Targets: targets}}
+			sc := &EnhancedStatisticsCollector{config: config, metrics: &models.ComprehensiveMetrics{}}
+			sc.resetSLA()
+			for i := 0; i < tt.customers; i++ {
+				// system time = wait, so both targets see the same customers
+				sc.recordSLA(tt.wait(i), tt.wait(i))
+			}
+			sc.calculateSLA()
+
+			within, exceeds := sc.metrics.SLA[0], sc.metrics.SLA[1]
+			if math.Abs(within.Attained-tt.within) > 1e-12 {
+				t.Errorf("attained %.6f, want %.6f", within.Attained, tt.within)
+			}
+			if math.Abs(within.Confidence[0]-(tt.within-tt.halfWidth)) > 1e-6 || math.Abs(within.Confidence[1]-(tt.within+tt.halfWidth)) > 1e-6 {
+				t.Errorf("interval [%.6f, %.6f], want %.6f +- %.6f", within.Confidence[0], within.Confidence[1], tt.within, tt.halfWidth)
+			}
+			if !within.Passed {
+				t.Errorf("%.2f within against a 0.7 target should pass", within.Attained)
+			}
+
+			// exceedance reports the complement and mirrors the interval
+			if math.Abs(exceeds.Attained-(1-tt.within)) > 1e-12 ||
+				math.Abs(exceeds.Confidence[0]-(1-within.Confidence[1])) > 1e-12 ||
+				math.Abs(exceeds.Confidence[1]-(1-within.Confidence[0])) > 1e-12 {
+				t.Errorf("exceedance %.6f [%.6f, %.6f] does not mirror %.6f [%.6f, %.6f]",
+					exceeds.Attained, exceeds.Confidence[0], exceeds.Confidence[1], within.Attained, within.Confidence[0], within.Confidence[1])
+			}
+			if exceeds.Passed {
+				t.Errorf("%.2f exceeding against a 0.2 limit should fail", exceeds.Attained)
+			}
+		})
+	}
+}
+
+func TestValidateSLARejectsDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []models.SLATarget
+		valid   bool
+	}{
+		{"distinct", []models.SLATarget{
+			{Metric: slaWaitTime, Threshold: 5, Target: 0.8},
+			{Metric: slaWaitTime, Threshold: 10, Target: 0.9},
+			{Metric: slaWaitTime, Type: slaExceeds, Threshold: 5, Target: 0.1},
+		}, true},
+		{"same fraction, different target", []models.SLATarget{
+			{Metric: slaWaitTime, Threshold: 5, Target: 0.8},
+			{Metric: slaWaitTime, Type: slaWithin, Threshold: 5, Target: 0.9},
+		}, false},
+		{"same name", []models.SLATarget{
+			{Name: "answered", Metric: slaWaitTime, Threshold: 5, Target: 0.8},
+			{Name: "answered", Metric: slaSystemTime, Threshold: 5, Target: 0.8},
+		}, false},
+	}
+	for _, tt := range tests {
+		config := &models.SimulationConfig{SLA: models.SLAConfig{Targets: tt.targets}}
+		if err := ValidateSLA(config); (err == nil) != tt.valid {
+			t.Errorf("%s: error %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
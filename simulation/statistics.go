@@ -3,32 +3,164 @@ package simulation
 import (
 	"des/models"
 	"math"
-	"sort"
 )
 
 type EnhancedStatisticsCollector struct {
-	metrics        *models.ComprehensiveMetrics
-	customerStats  []*models.CustomerStats
-	waitTimes      []float64
-	systemTimes    []float64
-	config         *models.SimulationConfig
-	maxQueueLength int
-	maxWaitTime    float64
+	metrics              *models.ComprehensiveMetrics
+	customerStats        []*models.CustomerStats
+	waitTimes            []float64
+	systemTimes          []float64
+	arrivalServiceTimes  []float64
+	arrivalInterarrivals []float64
+	firstArrivalID       int
+	config               *models.SimulationConfig
+	seed                 int64
+	maxQueueLength       int
+	maxWaitTime          float64
+	completions          int
+	warmupTime           float64
+	warmupCustomers      int
+	warmedUp             bool
+	observationStart     float64
+	baseServed           int
+	baseArrived          int
+	baseRejected         int
+	baseCompleted        int
+	baseInSystem         int
+	regeneration         *regenerationAccumulator
+	currentCycle         regenerationCycle
+	cycleStart           float64
+	cycleActive          bool
+	queueHistogram       *sectionedHistogram
+	systemHistogram      *sectionedHistogram
+	waitMoments          welford
+	systemMoments        welford
+	waitStream           *streamingSeries
+	busyPeriods          *periodAccumulator
+	idlePeriods          *periodAccumulator
+	busyCustomers        *periodAccumulator
+	busyStart            float64
+	busyOpen             bool
+	busyServed           int
+	idleStart            float64
+	idleOpen             bool
+	interDepartures      welford
+	departureCorrelation *autocorrelationAccumulator
+	lastDeparture        float64
+	departureSeen        bool
+	systemStream         *streamingSeries
+	slaSeries            []*indicatorSeries
+	costs                *costAccumulator
+	series               *timeSeriesAccumulator
 }
 
-func NewStatisticsCollector(config *models.SimulationConfig) *EnhancedStatisticsCollector {
-	return &EnhancedStatisticsCollector{
+// NewStatisticsCollector returns a collector for a run that draws its random
+// numbers from seed, which must already be resolved.
+func NewStatisticsCollector(config *models.SimulationConfig, seed int64) *EnhancedStatisticsCollector {
+	sc := &EnhancedStatisticsCollector{
 		metrics: &models.ComprehensiveMetrics{
 			WaitTimePercentiles:   make(map[string]float64),
 			SystemTimePercentiles: make(map[string]float64),
 		},
-		customerStats:  []*models.CustomerStats{},
-		waitTimes:      []float64{},
-		systemTimes:    []float64{},
-		config:         config,
-		maxQueueLength: 0,
-		maxWaitTime:    0,
+		customerStats:   []*models.CustomerStats{},
+		waitTimes:       []float64{},
+		systemTimes:     []float64{},
+		config:          config,
+		seed:            seed,
+		maxQueueLength:  0,
+		maxWaitTime:     0,
+		warmupTime:      config.Warmup.Time,
+		warmupCustomers: config.Warmup.Customers,
+		warmedUp:        config.Warmup.Time <= 0 && config.Warmup.Customers <= 0,
+		queueHistogram:  newSectionedHistogram(),
+		series:          newTimeSeriesAccumulator(config),
+		systemHistogram: newSectionedHistogram(),
+		regeneration:    newRegenerationAccumulator(config),
 	}
+	sc.resetStreams()
+	sc.resetPeriods(true, 0)
+	sc.resetDepartures()
+	sc.resetSLA()
+	sc.resetCosts()
+	return sc
+}
+
+// resetStreams starts empty streaming series when streaming is enabled.
+func (sc *EnhancedStatisticsCollector) resetStreams() {
+	if sc.config.Streaming.Enabled {
+		sc.waitStream = newStreamingSeries(sc.config.Streaming.RelativeAccuracy)
+		sc.systemStream = newStreamingSeries(sc.config.Streaming.RelativeAccuracy)
+	}
+}
+
+// SetWarmupCustomers overrides the configured warm-up with a customer count,
+// typically one chosen by DetectWarmup.
+func (sc *EnhancedStatisticsCollector) SetWarmupCustomers(customers int) {
+	sc.warmupTime = 0
+	sc.warmupCustomers = customers
+	sc.warmedUp = customers <= 0
+}
+
+// AdvanceTo integrates the time-weighted accumulators from the last event up
+// to time t. If the warm-up time falls inside the interval the accumulators
+// are reset exactly at that instant.
+func (sc *EnhancedStatisticsCollector) AdvanceTo(state *models.SystemState, t float64) {
+	if sc.series != nil {
+		sc.series.advance(len(state.Queue), state.BusyServers, state.LastEventTime, t)
+	}
+	if !sc.warmedUp && sc.warmupTime > 0 && t >= sc.warmupTime {
+		sc.UpdatePreEvent(state, sc.warmupTime-state.LastEventTime)
+		sc.ResetAccumulators(state, sc.warmupTime)
+		sc.UpdatePreEvent(state, t-sc.warmupTime)
+		return
+	}
+	sc.UpdatePreEvent(state, t-state.LastEventTime)
+}
+
+// CheckWarmup resets the accumulators once the configured number of
+// customers has completed service.
+func (sc *EnhancedStatisticsCollector) CheckWarmup(state *models.SystemState) {
+	if !sc.warmedUp && sc.warmupCustomers > 0 && sc.completions >= sc.warmupCustomers {
+		sc.ResetAccumulators(state, state.Clock)
+	}
+}
+
+// ResetAccumulators discards everything observed before time t so that the
+// final metrics only describe the post-warm-up period.
+func (sc *EnhancedStatisticsCollector) ResetAccumulators(state *models.SystemState, t float64) {
+	state.AreaUnderQ = 0
+	state.AreaUnderB = 0
+	state.TotalDelay = 0
+
+	sc.metrics.WarmupTime = t
+	sc.metrics.WarmupCustomers = sc.completions
+	sc.customerStats = []*models.CustomerStats{}
+	sc.waitTimes = []float64{}
+	sc.systemTimes = []float64{}
+	sc.arrivalServiceTimes = nil
+	sc.arrivalInterarrivals = nil
+	sc.firstArrivalID = 0
+	sc.maxQueueLength = len(state.Queue)
+	sc.maxWaitTime = 0
+	sc.regeneration = newRegenerationAccumulator(sc.config)
+	sc.cycleActive = false
+	sc.queueHistogram = newSectionedHistogram()
+	sc.systemHistogram = newSectionedHistogram()
+	sc.waitMoments = welford{}
+	sc.systemMoments = welford{}
+	sc.resetStreams()
+	sc.resetPeriods(false, t)
+	sc.resetDepartures()
+	sc.resetSLA()
+	sc.resetCosts()
+
+	sc.warmedUp = true
+	sc.observationStart = t
+	sc.baseServed = state.CustomersServed
+	sc.baseArrived = state.TotalCustomers
+	sc.baseRejected = state.RejectedCustomers
+	sc.baseCompleted = sc.completions
+	sc.baseInSystem = len(state.Queue) + state.BusyServers
 }
 
 func (sc *EnhancedStatisticsCollector) UpdatePreEvent(state *models.SystemState, timeDiff float64) {
@@ -37,43 +169,105 @@ func (sc *EnhancedStatisticsCollector) UpdatePreEvent(state *models.SystemState,
 	}
 	currentQueueLength := len(state.Queue)
 	state.AreaUnderQ += float64(currentQueueLength) * timeDiff
+	sc.queueHistogram.add(currentQueueLength, timeDiff)
 
 	if currentQueueLength > sc.maxQueueLength {
 		sc.maxQueueLength = currentQueueLength
 	}
 
-	if state.ServerBusy {
-		state.AreaUnderB += timeDiff
+	state.AreaUnderB += float64(state.BusyServers) * timeDiff
+	sc.systemHistogram.add(currentQueueLength+state.BusyServers, timeDiff)
+	if sc.costs != nil {
+		sc.costs.advance(currentQueueLength, state.BusyServers, timeDiff)
+	}
+
+	if sc.cycleActive {
+		sc.currentCycle.areaQ += float64(currentQueueLength) * timeDiff
+		sc.currentCycle.areaBusy += float64(state.BusyServers) * timeDiff
 	}
 }
 
+// RecordArrival keeps the sampled inputs of every arriving customer, admitted
+// or not, for use as control variates.
+func (sc *EnhancedStatisticsCollector) RecordArrival(customer *models.Customer) {
+	if sc.cycleActive {
+		sc.currentCycle.arrivals++
+		if customer.Status == models.CustomerRejected {
+			sc.currentCycle.rejected++
+		}
+	}
+	if sc.costs != nil {
+		sc.costs.arrival(customer.Status == models.CustomerRejected)
+	}
+	if sc.series != nil {
+		sc.series.arrival(customer.ArrivalTime, customer.Status == models.CustomerRejected)
+	}
+	if !sc.config.ControlVariates.Enabled || sc.config.Streaming.Enabled {
+		return
+	}
+	if len(sc.arrivalServiceTimes) == 0 {
+		sc.firstArrivalID = customer.ID
+	}
+	sc.arrivalServiceTimes = append(sc.arrivalServiceTimes, customer.ServiceTime)
+	sc.arrivalInterarrivals = append(sc.arrivalInterarrivals, customer.InterarrivalTime)
+}
+
 func (sc *EnhancedStatisticsCollector) RecordCustomerCompletion(customer *models.Customer) {
 	waitTime := math.Max(0, customer.ServiceStart-customer.ArrivalTime)
 	systemTime := math.Max(0, customer.ExitTime-customer.ArrivalTime)
-	stats := &models.CustomerStats{
-		WaitTime:   waitTime,
-		SystemTime: systemTime,
+	sc.waitMoments.add(waitTime)
+	sc.systemMoments.add(systemTime)
+	if sc.config.Streaming.Enabled {
+		sc.waitStream.add(waitTime)
+		sc.systemStream.add(systemTime)
+	} else {
+		stats := &models.CustomerStats{
+			CustomerID: customer.ID,
+			WaitTime:   waitTime,
+			SystemTime: systemTime,
+		}
+		sc.customerStats = append(sc.customerStats, stats)
+		sc.waitTimes = append(sc.waitTimes, waitTime)
+		sc.systemTimes = append(sc.systemTimes, systemTime)
+	}
+	sc.completions++
+	sc.busyServed++
+	sc.recordDeparture(customer.ExitTime)
+	sc.recordSLA(waitTime, systemTime)
+	if sc.costs != nil {
+		sc.costs.completion()
+	}
+	if sc.series != nil {
+		sc.series.completion(customer.ExitTime, waitTime)
+	}
+	if sc.cycleActive {
+		sc.currentCycle.customers++
+		sc.currentCycle.waitSum += waitTime
+		sc.currentCycle.systemSum += systemTime
 	}
-	sc.customerStats = append(sc.customerStats, stats)
-	sc.waitTimes = append(sc.waitTimes, waitTime)
-	sc.systemTimes = append(sc.systemTimes, systemTime)
 	if waitTime > sc.maxWaitTime {
 		sc.maxWaitTime = waitTime
 	}
 }
 
 func (sc *EnhancedStatisticsCollector) CalculateFinalMetrics(state *models.SystemState) *models.ComprehensiveMetrics {
-	sc.metrics.TotalCustomers = state.TotalCustomers
-	sc.metrics.RejectedCustomers = state.RejectedCustomers
+	elapsed := state.Clock - sc.observationStart
+	served := state.CustomersServed - sc.baseServed
+	arrived := state.TotalCustomers - sc.baseArrived
+	rejected := state.RejectedCustomers - sc.baseRejected
+	servers := serverCount(sc.config)
+
+	sc.metrics.TotalCustomers = arrived
+	sc.metrics.RejectedCustomers = rejected
 	sc.metrics.MaxQueueLength = sc.maxQueueLength
 	sc.metrics.MaxWaitTime = sc.maxWaitTime
 
-	if state.Clock > 0 {
-		sc.metrics.AverageQueueLength = state.AreaUnderQ / state.Clock
-		sc.metrics.ServerUtilization = state.AreaUnderB / state.Clock
+	if elapsed > 0 {
+		sc.metrics.AverageQueueLength = state.AreaUnderQ / elapsed
+		sc.metrics.ServerUtilization = state.AreaUnderB / elapsed / float64(servers)
 		sc.metrics.ServerBusyTime = state.AreaUnderB
-		sc.metrics.ServerIdleTime = state.Clock - state.AreaUnderB
-		sc.metrics.Throughput = float64(state.CustomersServed) / state.Clock
+		sc.metrics.ServerIdleTime = elapsed*float64(servers) - state.AreaUnderB
+		sc.metrics.Throughput = float64(served) / elapsed
 	} else {
 		sc.metrics.AverageQueueLength = 0
 		sc.metrics.ServerUtilization = 0
@@ -82,74 +276,45 @@ func (sc *EnhancedStatisticsCollector) CalculateFinalMetrics(state *models.Syste
 		sc.metrics.Throughput = 0
 	}
 
-	if state.CustomersServed > 0 {
-		sc.metrics.AverageWaitTime = state.TotalDelay / float64(state.CustomersServed)
-	} else {
-		sc.metrics.AverageWaitTime = 0
-	}
+	// Wait and system time are both averaged over the customers that
+	// completed service, so that Wq and W describe the same population.
+	sc.metrics.AverageWaitTime = sc.waitMoments.mean
+	sc.metrics.AverageSystemTime = sc.systemMoments.mean
 
-	totalSystemTime := 0.0
-	for _, stats := range sc.customerStats {
-		totalSystemTime += stats.SystemTime
-	}
-	if len(sc.customerStats) > 0 {
-		sc.metrics.AverageSystemTime = totalSystemTime / float64(len(sc.customerStats))
-	} else {
-		sc.metrics.AverageSystemTime = 0
-	}
-
-	sc.metrics.AverageInSystem = sc.metrics.AverageQueueLength + sc.metrics.ServerUtilization
-	if state.Clock > 0 && sc.config.MaxQueueSize > 0 {
-		sc.metrics.QueueProbability = state.AreaUnderQ / state.Clock / float64(sc.config.MaxQueueSize)
+	sc.metrics.AverageInSystem = sc.metrics.AverageQueueLength + sc.metrics.ServerUtilization*float64(servers)
+	if elapsed > 0 && sc.config.MaxQueueSize > 0 {
+		sc.metrics.QueueProbability = state.AreaUnderQ / elapsed / float64(sc.config.MaxQueueSize)
 	} else {
 		sc.metrics.QueueProbability = 0
 	}
 
-	if state.TotalCustomers > 0 {
-		sc.metrics.BlockingProbability = float64(state.RejectedCustomers) / float64(state.TotalCustomers)
+	if arrived > 0 {
+		sc.metrics.BlockingProbability = float64(rejected) / float64(arrived)
 	} else {
 		sc.metrics.BlockingProbability = 0
 	}
 
 	sc.calculateVariances()
 	sc.calculatePercentiles()
+	sc.metrics.SystemSizeDistribution = sc.systemHistogram.overall.distribution()
+	sc.metrics.QueueLengthDistribution = sc.queueHistogram.overall.distribution()
 	sc.calculateConfidenceIntervals()
+	sc.calculateControlVariates()
+	sc.calculateRegenerative()
+	sc.calculateBusyPeriods()
+	sc.calculateDepartureProcess()
+	sc.calculateSLA()
+	sc.calculateCost()
+	sc.checkConsistency(state, elapsed, arrived, rejected)
 
 	return sc.metrics
 }
 
+// calculateVariances uses the one-pass Welford moments, so it needs no
+// stored series.
 func (sc *EnhancedStatisticsCollector) calculateVariances() {
-	if len(sc.waitTimes) < 2 {
-		sc.metrics.WaitTimeVariance = 0
-		sc.metrics.SystemTimeVariance = 0
-		return
-	}
-
-	waitSumSq := 0.0
-	for _, wt := range sc.waitTimes {
-		waitSumSq += (wt - sc.metrics.AverageWaitTime) * (wt - sc.metrics.AverageWaitTime)
-	}
-	sc.metrics.WaitTimeVariance = waitSumSq / float64(len(sc.waitTimes))
-
-	systemSumSq := 0.0
-	for _, st := range sc.systemTimes {
-		systemSumSq += (st - sc.metrics.AverageSystemTime) * (st - sc.metrics.AverageSystemTime)
-	}
-	sc.metrics.SystemTimeVariance = systemSumSq / float64(len(sc.systemTimes))
-}
-
-func (sc *EnhancedStatisticsCollector) calculatePercentiles() {
-	if len(sc.waitTimes) == 0 {
-		return
-	}
-	waitTimes := append([]float64{}, sc.waitTimes...)
-	sort.Float64s(waitTimes)
-	sc.metrics.WaitTimePercentiles = map[string]float64{
-		"50th": sc.calculatePercentile(waitTimes, 0.5),
-		"75th": sc.calculatePercentile(waitTimes, 0.75),
-		"90th": sc.calculatePercentile(waitTimes, 0.9),
-		"95th": sc.calculatePercentile(waitTimes, 0.95),
-	}
+	sc.metrics.WaitTimeVariance = sc.waitMoments.variance()
+	sc.metrics.SystemTimeVariance = sc.systemMoments.variance()
 }
 
 func (sc *EnhancedStatisticsCollector) calculatePercentile(data []float64, percentile float64) float64 {
@@ -166,24 +331,55 @@ func (sc *EnhancedStatisticsCollector) calculatePercentile(data []float64, perce
 	return data[lower]*(1-weight) + data[upper]*weight
 }
 
+// calculateConfidenceIntervals builds intervals for the mean wait and system
+// time. Per-customer times are strongly autocorrelated, so unless the iid
+// method is requested the half-widths come from batch means; when the series
+// is too short for batch means the intervals are NaN rather than the iid
+// intervals, which would be too narrow.
 func (sc *EnhancedStatisticsCollector) calculateConfidenceIntervals() {
-	if len(sc.waitTimes) < 2 {
+	level := confidenceLevel(sc.config)
+	sc.metrics.ConfidenceLevel = level
+	sc.metrics.WaitTimeBatchMeans = nil
+	sc.metrics.SystemTimeBatchMeans = nil
+
+	n := sc.waitMoments.n
+	if n < 2 {
 		sc.metrics.WaitTimeConfidence = [2]float64{0, 0}
 		sc.metrics.SystemTimeConfidence = [2]float64{0, 0}
 		return
 	}
 
-	waitStdErr := math.Sqrt(sc.metrics.WaitTimeVariance/float64(len(sc.waitTimes))) * 1.96
-	sc.metrics.WaitTimeConfidence = [2]float64{
-		sc.metrics.AverageWaitTime - waitStdErr,
-		sc.metrics.AverageWaitTime + waitStdErr,
+	method := sc.config.OutputAnalysis.Method
+	if method == "" {
+		method = methodBatchMeans
 	}
-
-	if len(sc.systemTimes) >= 2 {
-		systemStdErr := math.Sqrt(sc.metrics.SystemTimeVariance/float64(len(sc.systemTimes))) * 1.96
-		sc.metrics.SystemTimeConfidence = [2]float64{
-			sc.metrics.AverageSystemTime - systemStdErr,
-			sc.metrics.AverageSystemTime + systemStdErr,
+	var waitHalfWidth, systemHalfWidth float64
+	if method == methodIID {
+		waitHalfWidth = TCritical(level, n-1) * math.Sqrt(sc.metrics.WaitTimeVariance/float64(n))
+		systemHalfWidth = TCritical(level, n-1) * math.Sqrt(sc.metrics.SystemTimeVariance/float64(n))
+	} else {
+		if sc.config.Streaming.Enabled {
+			sc.metrics.WaitTimeBatchMeans = sc.waitStream.batchMeansInterval(level)
+			sc.metrics.SystemTimeBatchMeans = sc.systemStream.batchMeansInterval(level)
+		} else {
+			sc.metrics.WaitTimeBatchMeans = batchMeansInterval(sc.waitTimes, method, sc.config.OutputAnalysis.Batches, level)
+			sc.metrics.SystemTimeBatchMeans = batchMeansInterval(sc.systemTimes, method, sc.config.OutputAnalysis.Batches, level)
+		}
+		waitHalfWidth, systemHalfWidth = math.NaN(), math.NaN()
+		if sc.metrics.WaitTimeBatchMeans != nil {
+			waitHalfWidth = sc.metrics.WaitTimeBatchMeans.HalfWidth
 		}
+		if sc.metrics.SystemTimeBatchMeans != nil {
+			systemHalfWidth = sc.metrics.SystemTimeBatchMeans.HalfWidth
+		}
+	}
+
+	sc.metrics.WaitTimeConfidence = [2]float64{
+		sc.metrics.AverageWaitTime - waitHalfWidth,
+		sc.metrics.AverageWaitTime + waitHalfWidth,
+	}
+	sc.metrics.SystemTimeConfidence = [2]float64{
+		sc.metrics.AverageSystemTime - systemHalfWidth,
+		sc.metrics.AverageSystemTime + systemHalfWidth,
 	}
 }
@@ -0,0 +1,176 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	stopTime      = "time"
+	stopCustomers = "customers"
+	stopArrivals  = "arrivals"
+	stopEvents    = "events"
+	stopWallClock = "wallclock"
+	stopAny       = "any"
+	stopAll       = "all"
+
+	StopReasonEventListEmpty = "event_list_empty"
+	StopReasonMaxCustomers   = "max_customers"
+	StopReasonTimeLimit      = "time_limit"
+	StopReasonUser           = "user"
+)
+
+// stopPolicy decides when a run ends. Rules are combined with "any"
+// (whichever is met first) or "all"; max_customers and time_limit always
+// apply on top as "any" guards.
+type stopPolicy struct {
+	rules []models.StopRule
+	all   bool
+}
+
+func newStopPolicy(config *models.SimulationConfig) stopPolicy {
+	condition := config.StopCondition
+	var policy stopPolicy
+	switch condition.Type {
+	case stopAny, stopAll:
+		policy.all = condition.Type == stopAll
+		policy.rules = append(policy.rules, condition.Conditions...)
+	case stopPrecision:
+		policy.rules = []models.StopRule{{Type: stopPrecision}, {Type: stopTime}}
+	case "", stopTime:
+		policy.rules = []models.StopRule{{Type: stopTime, Value: config.SimulationTime}}
+	default:
+		policy.rules = []models.StopRule{{Type: condition.Type, Value: condition.Value}}
+	}
+
+	for i, rule := range policy.rules {
+		if rule.Type == stopTime && rule.Value <= 0 {
+			policy.rules[i].Value = config.SimulationTime
+		}
+	}
+	return policy
+}
+
+// ValidateStopCondition rejects unknown rule types and composites without
+// rules.
+func ValidateStopCondition(config *models.SimulationConfig) error {
+	condition := config.StopCondition
+	rules := []models.StopRule{{Type: condition.Type}}
+	if condition.Type == stopAny || condition.Type == stopAll {
+		if len(condition.Conditions) == 0 {
+			return fmt.Errorf("stop condition %q needs at least one entry in conditions", condition.Type)
+		}
+		rules = condition.Conditions
+	}
+	for _, rule := range rules {
+		switch rule.Type {
+		case "", stopTime, stopCustomers, stopArrivals, stopEvents, stopWallClock, stopPrecision:
+		default:
+			return fmt.Errorf("unknown stop condition type %q", rule.Type)
+		}
+	}
+	if condition.Type == stopPrecision {
+		return ValidatePrecision(config)
+	}
+	if hasRule(rules, stopPrecision) {
+		if err := ValidatePrecision(config); err != nil {
+			return err
+		}
+		for _, name := range config.StopCondition.Precision.Metrics {
+			if !singleRunPrecisionMetrics[name] {
+				return fmt.Errorf("metric %q cannot be used in a composite precision rule", name)
+			}
+		}
+	}
+	return nil
+}
+
+func hasRule(rules []models.StopRule, kind string) bool {
+	for _, rule := range rules {
+		if rule.Type == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// arrivalHorizon is the time after which no further arrivals are scheduled:
+// the earliest time rule when any rule ends the run, otherwise unbounded.
+func (policy stopPolicy) arrivalHorizon() float64 {
+	horizon := math.Inf(1)
+	if policy.all {
+		return horizon
+	}
+	for _, rule := range policy.rules {
+		if rule.Type == stopTime {
+			horizon = math.Min(horizon, rule.Value)
+		}
+	}
+	return horizon
+}
+
+// ShouldStop evaluates the stop policy before the next event is processed
+// and records the reason when the run has to end.
+func (sim *DiscreteEventSimulator) ShouldStop() bool {
+	if sim.stopReason != "" {
+		return true
+	}
+	next := sim.events.PeekNextEvent()
+
+	if sim.config.MaxCustomers > 0 && sim.state.TotalCustomers >= sim.config.MaxCustomers {
+		sim.stopReason = StopReasonMaxCustomers
+		return true
+	}
+	if limit := sim.config.StopCondition.TimeLimit; limit > 0 && time.Since(sim.startedAt).Seconds() >= limit {
+		sim.stopReason = StopReasonTimeLimit
+		return true
+	}
+
+	var met []string
+	for _, rule := range sim.stopPolicy.rules {
+		if sim.ruleMet(rule, next) {
+			met = append(met, rule.Type)
+			if rule.Type == stopTime {
+				sim.horizon = math.Min(sim.horizon, rule.Value)
+			}
+		}
+	}
+	switch {
+	case len(met) > 0 && (!sim.stopPolicy.all || len(met) == len(sim.stopPolicy.rules)):
+		sim.stopReason = met[0]
+		if sim.stopPolicy.all {
+			sim.stopReason = stopAll
+		}
+	case next == nil:
+		sim.stopReason = StopReasonEventListEmpty
+	default:
+		sim.horizon = math.Inf(1)
+		return false
+	}
+	return true
+}
+
+// Stop ends the run early for an external reason such as a user request.
+func (sim *DiscreteEventSimulator) Stop(reason string) {
+	sim.stopReason = reason
+}
+
+func (sim *DiscreteEventSimulator) ruleMet(rule models.StopRule, next *models.Event) bool {
+	switch rule.Type {
+	case stopTime:
+		return next == nil || next.Timestamp > rule.Value
+	case stopCustomers:
+		return float64(sim.stats.completions) >= rule.Value
+	case stopArrivals:
+		return float64(sim.state.TotalCustomers) >= rule.Value
+	case stopEvents:
+		return float64(sim.state.EventsProcessed) >= rule.Value
+	case stopWallClock:
+		return time.Since(sim.startedAt).Seconds() >= rule.Value
+	case stopPrecision:
+		return sim.precisionReached()
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package simulation
+
+import (
+	"des/models"
+	"testing"
+)
+
+func TestStopRules(t *testing.T) {
+	// at arrival rate 0.5, 30 customers take about 60 time units; the
+	// customers rule counts departures
+	tests := []struct {
+		name       string
+		condition  models.StopCondition
+		wantReason string
+		check      func(state *models.SystemState, completed int) bool
+	}{
+		{
+			"any stops at the customer count",
+			models.StopCondition{Type: stopAny, Conditions: []models.StopRule{{Type: stopCustomers, Value: 30}, {Type: stopTime, Value: 1000}}},
+			stopCustomers,
+			func(s *models.SystemState, completed int) bool { return completed == 30 && s.Clock < 1000 },
+		},
+		{
+			"any stops at the time limit",
+			models.StopCondition{Type: stopAny, Conditions: []models.StopRule{{Type: stopCustomers, Value: 1000}, {Type: stopTime, Value: 50}}},
+			stopTime,
+			func(s *models.SystemState, completed int) bool { return completed < 1000 && s.Clock <= 50 },
+		},
+		{
+			"all waits for the customer count after the time",
+			models.StopCondition{Type: stopAll, Conditions: []models.StopRule{{Type: stopCustomers, Value: 30}, {Type: stopTime, Value: 10}}},
+			stopAll,
+			func(s *models.SystemState, completed int) bool { return completed == 30 && s.Clock > 10 },
+		},
+		{
+			"all waits for the time after the customer count",
+			models.StopCondition{Type: stopAll, Conditions: []models.StopRule{{Type: stopCustomers, Value: 5}, {Type: stopTime, Value: 200}}},
+			stopAll,
+			func(s *models.SystemState, completed int) bool {
+				return completed > 5 && s.Clock > 150 && s.Clock <= 200
+			},
+		},
+		{
+			"arrivals",
+			models.StopCondition{Type: stopArrivals, Value: 40},
+			stopArrivals,
+			func(s *models.SystemState, completed int) bool { return s.TotalCustomers == 40 },
+		},
+		{
+			"events",
+			models.StopCondition{Type: stopEvents, Value: 100},
+			stopEvents,
+			func(s *models.SystemState, completed int) bool { return s.EventsProcessed == 100 },
+		},
+	}
+	for _, tt := range tests {
+		config := mm1TestConfig(0.5, 1, 10000)
+		config.StopCondition = tt.condition
+		config.StopCondition.AutomaticMode = true
+		if err := ValidateStopCondition(config); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		sim := newSilentSimulator(config, config.Random.Seed, false)
+		results := sim.Run()
+		if results.StopReason != tt.wantReason {
+			t.Errorf("%s: stop reason %q, want %q", tt.name, results.StopReason, tt.wantReason)
+		}
+		if s := results.State; !tt.check(s, sim.stats.completions) {
+			t.Errorf("%s: stopped at clock %.2f with %d arrivals, %d departures, %d events",
+				tt.name, s.Clock, s.TotalCustomers, sim.stats.completions, s.EventsProcessed)
+		}
+	}
+}
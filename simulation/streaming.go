@@ -0,0 +1,204 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"sort"
+)
+
+const (
+	defaultRelativeAccuracy = 0.01
+	minIndexableValue       = 1e-9
+)
+
+// welford accumulates the mean and variance of a series in one pass with
+// constant memory.
+type welford struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+func (w *welford) add(x float64) {
+	w.n++
+	delta := x - w.mean
+	w.mean += delta / float64(w.n)
+	w.m2 += delta * (x - w.mean)
+}
+
+// variance returns the population (n) variance, matching calculateVariances.
+func (w *welford) variance() float64 {
+	if w.n < 2 {
+		return 0
+	}
+	return w.m2 / float64(w.n)
+}
+
+// ddSketch is a quantile sketch with relative accuracy alpha: every value is
+// counted in the logarithmic bucket ceil(log_gamma(x)), gamma =
+// (1+alpha)/(1-alpha), and any quantile it returns lies within a factor
+// alpha of the true sample quantile. Values below minIndexableValue (such as
+// zero waits) share one bucket.
+type ddSketch struct {
+	gamma    float64
+	logGamma float64
+	bins     map[int]float64
+	zeros    float64
+	count    float64
+	min      float64
+	max      float64
+}
+
+func newDDSketch(alpha float64) *ddSketch {
+	gamma := (1 + alpha) / (1 - alpha)
+	return &ddSketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		bins:     make(map[int]float64),
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+func (s *ddSketch) add(x float64) {
+	if x < minIndexableValue {
+		s.zeros++
+	} else {
+		s.bins[int(math.Ceil(math.Log(x)/s.logGamma))]++
+	}
+	s.count++
+	s.min = math.Min(s.min, x)
+	s.max = math.Max(s.max, x)
+}
+
+func (s *ddSketch) merge(other *ddSketch) {
+	for index, count := range other.bins {
+		s.bins[index] += count
+	}
+	s.zeros += other.zeros
+	s.count += other.count
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+}
+
+func (s *ddSketch) quantile(p float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	rank := p * (s.count - 1)
+	if rank < s.zeros {
+		return math.Max(s.min, 0)
+	}
+	indices := make([]int, 0, len(s.bins))
+	for index := range s.bins {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	cumulative := s.zeros
+	for _, index := range indices {
+		cumulative += s.bins[index]
+		if cumulative > rank {
+			value := 2 * math.Pow(s.gamma, float64(index)) / (s.gamma + 1)
+			return math.Max(s.min, math.Min(s.max, value))
+		}
+	}
+	return s.max
+}
+
+type streamingBatch struct {
+	sum    float64
+	count  int
+	sketch *ddSketch
+}
+
+// streamingSeries replaces a stored per-customer series on long runs. It
+// keeps a sketch of the whole series plus at most maxSections consecutive
+// batches, each with its own sketch, as timeSections counted in customers:
+// when the batches are full, neighbours are merged and the batch size
+// doubles. Memory is bounded by the number of sketch buckets, not
+// by the number of customers.
+type streamingSeries struct {
+	sketch  *ddSketch
+	batches *timeSections[*streamingBatch]
+}
+
+func newStreamingSeries(alpha float64) *streamingSeries {
+	if alpha <= 0 || alpha >= 1 {
+		alpha = defaultRelativeAccuracy
+	}
+	return &streamingSeries{
+		sketch: newDDSketch(alpha),
+		batches: newTimeSections(
+			func() *streamingBatch { return &streamingBatch{sketch: newDDSketch(alpha)} },
+			func(into, from *streamingBatch) {
+				into.sum += from.sum
+				into.count += from.count
+				into.sketch.merge(from.sketch)
+			},
+		),
+	}
+}
+
+func (ss *streamingSeries) add(x float64) {
+	ss.sketch.add(x)
+	ss.batches.advance(1, func(batch *streamingBatch, _ float64) {
+		batch.sum += x
+		batch.count++
+		batch.sketch.add(x)
+	})
+}
+
+// batchMeansInterval is the streaming counterpart of batchMeansInterval: a
+// t interval over the means of the complete batches. It returns nil with
+// fewer than minBatches batches.
+func (ss *streamingSeries) batchMeansInterval(level float64) *models.BatchMeansResult {
+	batches := ss.batches.complete()
+	if len(batches) < minBatches {
+		return nil
+	}
+	means := make([]float64, len(batches))
+	for i, batch := range batches {
+		means[i] = batch.sum / float64(batch.count)
+	}
+	b := len(means)
+	return &models.BatchMeansResult{
+		Method:              methodBatchMeans,
+		Batches:             b,
+		BatchSize:           int(ss.batches.length),
+		DegreesOfFreedom:    float64(b - 1),
+		HalfWidth:           TCritical(level, b-1) * math.Sqrt(sampleVariance(means)/float64(b)),
+		Lag1Autocorrelation: lagAutocorrelation(means, 1),
+	}
+}
+
+// quantiles estimates each level from the overall sketch, with a t interval
+// over the same quantile of the complete batches (sectioning).
+func (ss *streamingSeries) quantiles(levels []float64, level float64) (map[string]float64, []*models.QuantileEstimate) {
+	percentiles := make(map[string]float64)
+	if ss.sketch.count == 0 {
+		return percentiles, nil
+	}
+	batches := ss.batches.complete()
+
+	estimates := make([]*models.QuantileEstimate, len(levels))
+	for i, p := range levels {
+		estimate := &models.QuantileEstimate{
+			Level:    p,
+			Key:      quantileKey(p),
+			Estimate: ss.sketch.quantile(p),
+		}
+		estimate.Confidence = [2]float64{estimate.Estimate, estimate.Estimate}
+		if len(batches) >= 2 {
+			values := make([]float64, len(batches))
+			for j, batch := range batches {
+				values[j] = batch.sketch.quantile(p)
+			}
+			halfWidth := TCritical(level, len(values)-1) * math.Sqrt(sampleVariance(values)/float64(len(values)))
+			estimate.Confidence = [2]float64{estimate.Estimate - halfWidth, estimate.Estimate + halfWidth}
+		}
+		percentiles[estimate.Key] = estimate.Estimate
+		estimates[i] = estimate
+	}
+	return percentiles, estimates
+}
@@ -0,0 +1,112 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestWelfordMatchesTwoPass(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	exponential := make([]float64, 10000)
+	offset := make([]float64, 1000)
+	for i := range exponential {
+		exponential[i] = rng.ExpFloat64()
+	}
+	// a large offset makes the textbook sum-of-squares formula cancel badly
+	for i := range offset {
+		offset[i] = 1e9 + rng.NormFloat64()
+	}
+
+	tests := []struct {
+		name      string
+		values    []float64
+		tolerance float64
+	}{
+		{"hand computed", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 1e-12},
+		{"exponential", exponential, 1e-9},
+		// inputs carry rounding errors of about 1e-7 here
+		{"large offset", offset, 1e-6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w welford
+			for _, x := range tt.values {
+				w.add(x)
+			}
+			n := float64(len(tt.values))
+			mean := sampleMean(tt.values)
+			variance := sampleVariance(tt.values) * (n - 1) / n
+			if w.n != len(tt.values) || math.Abs(w.mean-mean) > 1e-12*math.Abs(mean) {
+				t.Errorf("n %d, mean %.12g; want %d, %.12g", w.n, w.mean, len(tt.values), mean)
+			}
+			if math.Abs(w.variance()-variance) > tt.tolerance*variance {
+				t.Errorf("variance %.12g, want %.12g", w.variance(), variance)
+			}
+		})
+	}
+
+	// the hand-computed series has mean 5 and population variance 4
+	var w welford
+	for _, x := range tests[0].values {
+		w.add(x)
+	}
+	if w.mean != 5 || w.variance() != 4 {
+		t.Errorf("mean %g, variance %g, want 5 and 4", w.mean, w.variance())
+	}
+}
+
+func TestDDSketchRelativeAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	exponential := make([]float64, 20000)
+	lognormal := make([]float64, 20000)
+	waits := make([]float64, 20000)
+	for i := range exponential {
+		exponential[i] = rng.ExpFloat64()
+		lognormal[i] = math.Exp(3 * rng.NormFloat64())
+		// an M/M/1-like wait: zero with probability 0.3
+		if rng.Float64() > 0.3 {
+			waits[i] = rng.ExpFloat64()
+		}
+	}
+
+	levels := []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 0.999, 1}
+	tests := []struct {
+		name   string
+		values []float64
+		alpha  float64
+	}{
+		{"exponential, 1%", exponential, 0.01},
+		{"exponential, 5%", exponential, 0.05},
+		{"heavy-tailed lognormal, 1%", lognormal, 0.01},
+		{"waits with zeros, 2%", waits, 0.02},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// two halves merged must give the same sketch as the whole series
+			whole, first, second := newDDSketch(tt.alpha), newDDSketch(tt.alpha), newDDSketch(tt.alpha)
+			for i, x := range tt.values {
+				whole.add(x)
+				if i < len(tt.values)/2 {
+					first.add(x)
+				} else {
+					second.add(x)
+				}
+			}
+			first.merge(second)
+
+			sorted := append([]float64{}, tt.values...)
+			sort.Float64s(sorted)
+			for _, p := range levels {
+				exact := sorted[int(p*float64(len(sorted)-1))]
+				for _, sketch := range []*ddSketch{whole, first} {
+					got := sketch.quantile(p)
+					if math.Abs(got-exact) > tt.alpha*exact*(1+1e-9) {
+						t.Errorf("quantile %g: %.6g, exact %.6g, outside relative accuracy %g", p, got, exact, tt.alpha)
+					}
+				}
+			}
+		})
+	}
+}
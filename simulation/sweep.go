@@ -0,0 +1,250 @@
+package simulation
+
+import (
+	"des/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultSweepMetrics are reported when sweep.metrics is empty.
+var defaultSweepMetrics = []string{
+	"average_wait_time",
+	"average_queue_length",
+	"server_utilization",
+	"blocking_probability",
+}
+
+// SweepMetrics returns the configured sweep metrics, or the defaults, after
+// checking that each one exists.
+func SweepMetrics(config *models.SimulationConfig) ([]string, error) {
+	names := config.Sweep.Metrics
+	if len(names) == 0 {
+		names = defaultSweepMetrics
+	}
+	for _, name := range names {
+		if _, err := LookupMetric(config, name); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// RunSweep runs every point of a sweep: one run per point, or the point's
+// replications when it has more than one. A time-based seed is resolved
+// once, so all points share their random numbers. The runs of all points
+// share one pool of Workers(config) goroutines; a point that replicates to
+// a precision target is a single job. progress, if not nil, counts jobs.
+func RunSweep(config *models.SimulationConfig, points []*models.SweepPoint, metrics []string, progress ProgressFunc) *models.SweepResult {
+	result := &models.SweepResult{
+		Metrics:         metrics,
+		ConfidenceLevel: confidenceLevel(config),
+		Points:          points,
+	}
+	if len(points) > 0 {
+		for _, setting := range points[0].Settings {
+			result.Fields = append(result.Fields, setting.Field)
+		}
+	}
+
+	type sweepJob struct{ point, run int }
+	var jobs []sweepJob
+	seed := ResolveSeed(config.Random.Seed)
+	seeds := make([][]int64, len(points))
+	runs := make([][]*models.ComprehensiveMetrics, len(points))
+	summaries := make([]*models.ReplicationSummary, len(points))
+	for i, point := range points {
+		if point.Config.Random.Seed == -1 {
+			point.Config.Random.Seed = seed
+		}
+		switch {
+		case point.Config.Replications > 1 && point.Config.StopCondition.Type == stopPrecision:
+			jobs = append(jobs, sweepJob{i, 0})
+			continue
+		case point.Config.Replications > 1:
+			point.Config = ResolveWarmup(point.Config)
+			seeds[i] = replicationSeeds(point.Config.Random.Seed, 0, point.Config.Replications)
+		default:
+			seeds[i] = []int64{point.Config.Random.Seed}
+		}
+		runs[i] = make([]*models.ComprehensiveMetrics, len(seeds[i]))
+		for r := range seeds[i] {
+			jobs = append(jobs, sweepJob{i, r})
+		}
+	}
+
+	runParallel(Workers(config), len(jobs), progress, func(j int) {
+		job := jobs[j]
+		point := points[job.point]
+		if seeds[job.point] == nil {
+			sequential := *point.Config
+			sequential.Parallelism = 1
+			summaries[job.point] = RunReplicationsToPrecision(&sequential, nil)
+			return
+		}
+		runs[job.point][job.run] = RunSilent(point.Config, seeds[job.point][job.run], false).Metrics
+	})
+
+	for i, point := range points {
+		point.Metrics = make([]*models.MetricSummary, len(metrics))
+		if point.Config.Replications > 1 {
+			if summaries[i] == nil {
+				summaries[i] = summarizeReplications(point.Config, seeds[i], runs[i])
+			}
+			point.Replications = summaries[i].Replications
+			for m, name := range metrics {
+				point.Metrics[m] = FindMetric(summaries[i], name)
+			}
+			result.HasConfidence = true
+			continue
+		}
+		point.Replications = 1
+		point.Metrics = singleRunMetrics(point.Config, runs[i][0], metrics)
+	}
+	return result
+}
+
+// singleRunMetrics wraps the values of one run, without an interval.
+func singleRunMetrics(config *models.SimulationConfig, run *models.ComprehensiveMetrics, metrics []string) []*models.MetricSummary {
+	summaries := make([]*models.MetricSummary, len(metrics))
+	for i, name := range metrics {
+		def, err := LookupMetric(config, name)
+		if err != nil {
+			continue
+		}
+		value := def.Value(run)
+		if math.IsNaN(value) {
+			continue
+		}
+		summaries[i] = &models.MetricSummary{
+			Name:       def.Name,
+			Label:      def.Label,
+			Values:     []float64{value},
+			Mean:       value,
+			Confidence: [2]float64{math.NaN(), math.NaN()},
+		}
+	}
+	return summaries
+}
+
+// WriteSweep exports the sweep table as JSON when path ends in .json and as
+// CSV otherwise, and records the file in the result.
+func WriteSweep(path string, result *models.SweepResult) error {
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = writeSweepJSON(path, result)
+	} else {
+		err = writeSweepCSV(path, result)
+	}
+	if err != nil {
+		return err
+	}
+	result.OutputFile = path
+	return nil
+}
+
+// sweepEstimate returns the mean and interval of a metric, NaN if missing.
+func sweepEstimate(metric *models.MetricSummary) (float64, [2]float64) {
+	if metric == nil {
+		return math.NaN(), [2]float64{math.NaN(), math.NaN()}
+	}
+	return metric.Mean, metric.Confidence
+}
+
+func writeSweepCSV(path string, result *models.SweepResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create sweep output: %v", err)
+	}
+	writer := csv.NewWriter(file)
+	header := append(append([]string{}, result.Fields...), "replications")
+	for _, name := range result.Metrics {
+		header = append(header, name)
+		if result.HasConfidence {
+			header = append(header, name+"_ci_low", name+"_ci_high")
+		}
+	}
+	writer.Write(header)
+
+	for _, point := range result.Points {
+		var row []string
+		for _, setting := range point.Settings {
+			row = append(row, setting.Value)
+		}
+		row = append(row, strconv.Itoa(point.Replications))
+		for _, metric := range point.Metrics {
+			mean, confidence := sweepEstimate(metric)
+			row = append(row, strconv.FormatFloat(mean, 'g', -1, 64))
+			if result.HasConfidence {
+				row = append(row, strconv.FormatFloat(confidence[0], 'g', -1, 64), strconv.FormatFloat(confidence[1], 'g', -1, 64))
+			}
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write sweep output: %v", err)
+	}
+	return file.Close()
+}
+
+type sweepJSONEstimate struct {
+	Mean   *float64 `json:"mean"`
+	CILow  *float64 `json:"ci_low,omitempty"`
+	CIHigh *float64 `json:"ci_high,omitempty"`
+}
+
+type sweepJSONPoint struct {
+	Settings     map[string]interface{}        `json:"settings"`
+	Replications int                           `json:"replications"`
+	Metrics      map[string]*sweepJSONEstimate `json:"metrics"`
+}
+
+// jsonNumber maps NaN, which JSON cannot represent, to null.
+func jsonNumber(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func writeSweepJSON(path string, result *models.SweepResult) error {
+	rows := make([]sweepJSONPoint, len(result.Points))
+	for i, point := range result.Points {
+		rows[i] = sweepJSONPoint{
+			Settings:     make(map[string]interface{}),
+			Replications: point.Replications,
+			Metrics:      make(map[string]*sweepJSONEstimate),
+		}
+		for _, setting := range point.Settings {
+			if number, err := strconv.ParseFloat(setting.Value, 64); err == nil {
+				rows[i].Settings[setting.Field] = number
+			} else {
+				rows[i].Settings[setting.Field] = setting.Value
+			}
+		}
+		for j, name := range result.Metrics {
+			mean, confidence := sweepEstimate(point.Metrics[j])
+			estimate := &sweepJSONEstimate{Mean: jsonNumber(mean)}
+			if point.Replications > 1 {
+				estimate.CILow, estimate.CIHigh = jsonNumber(confidence[0]), jsonNumber(confidence[1])
+			}
+			rows[i].Metrics[name] = estimate
+		}
+	}
+
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sweep output: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write sweep output: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package simulation
+
+import (
+	"bufio"
+	"des/models"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// timeBin accumulates one step of simulated time.
+type timeBin struct {
+	arrivals    float64
+	rejected    float64
+	completions float64
+	waitSum     float64
+	areaQ       float64
+	areaBusy    float64
+}
+
+// timeSeriesAccumulator records the whole run, warm-up included, in bins of
+// one step; a window is the sum of window/step consecutive bins.
+type timeSeriesAccumulator struct {
+	step    float64
+	width   int
+	servers float64
+	bins    []timeBin
+}
+
+// ValidateTimeSeries checks that the window is a whole number of steps.
+func ValidateTimeSeries(config *models.SimulationConfig) error {
+	window, step := config.TimeSeries.Window, timeSeriesStep(config)
+	if window < 0 || step < 0 {
+		return fmt.Errorf("window and step must be non-negative")
+	}
+	if window == 0 {
+		return nil
+	}
+	if step > window {
+		return fmt.Errorf("step %v exceeds window %v", step, window)
+	}
+	if ratio := window / step; math.Abs(ratio-math.Round(ratio)) > 1e-9 {
+		return fmt.Errorf("window %v must be a multiple of step %v", window, step)
+	}
+	return nil
+}
+
+// timeSeriesStep defaults the step to the window, giving adjacent windows.
+func timeSeriesStep(config *models.SimulationConfig) float64 {
+	if config.TimeSeries.Step > 0 {
+		return config.TimeSeries.Step
+	}
+	return config.TimeSeries.Window
+}
+
+func newTimeSeriesAccumulator(config *models.SimulationConfig) *timeSeriesAccumulator {
+	if config.TimeSeries.Window <= 0 {
+		return nil
+	}
+	step := timeSeriesStep(config)
+	return &timeSeriesAccumulator{
+		step:    step,
+		width:   int(math.Round(config.TimeSeries.Window / step)),
+		servers: float64(serverCount(config)),
+	}
+}
+
+func (ts *timeSeriesAccumulator) bin(t float64) *timeBin {
+	index := int(t / ts.step)
+	for len(ts.bins) <= index {
+		ts.bins = append(ts.bins, timeBin{})
+	}
+	return &ts.bins[index]
+}
+
+// advance integrates the queue length and busy servers from 'from' to 'to'.
+func (ts *timeSeriesAccumulator) advance(queue, busy int, from, to float64) {
+	for from < to {
+		end := math.Min(to, (math.Floor(from/ts.step)+1)*ts.step)
+		if end <= from {
+			// from sits on a bin boundary that floating point rounded down
+			end = math.Min(to, from+ts.step)
+		}
+		bin := ts.bin(from)
+		bin.areaQ += float64(queue) * (end - from)
+		bin.areaBusy += float64(busy) * (end - from)
+		from = end
+	}
+}
+
+func (ts *timeSeriesAccumulator) arrival(t float64, rejected bool) {
+	bin := ts.bin(t)
+	bin.arrivals++
+	if rejected {
+		bin.rejected++
+	}
+}
+
+func (ts *timeSeriesAccumulator) completion(t, wait float64) {
+	bin := ts.bin(t)
+	bin.completions++
+	bin.waitSum += wait
+}
+
+// points returns one point per full window ending at a multiple of step no
+// later than end. Mean wait and blocking are NaN in windows without
+// completions or arrivals.
+func (ts *timeSeriesAccumulator) points(end float64) []*models.TimeSeriesPoint {
+	complete := int(math.Floor(end/ts.step + 1e-9))
+	if complete > len(ts.bins) {
+		complete = len(ts.bins)
+	}
+	length := float64(ts.width) * ts.step
+
+	var points []*models.TimeSeriesPoint
+	for last := ts.width - 1; last < complete; last++ {
+		var sum timeBin
+		for _, bin := range ts.bins[last-ts.width+1 : last+1] {
+			sum.arrivals += bin.arrivals
+			sum.rejected += bin.rejected
+			sum.completions += bin.completions
+			sum.waitSum += bin.waitSum
+			sum.areaQ += bin.areaQ
+			sum.areaBusy += bin.areaBusy
+		}
+		point := &models.TimeSeriesPoint{
+			Start:       float64(last+1)*ts.step - length,
+			End:         float64(last+1) * ts.step,
+			ArrivalRate: sum.arrivals / length,
+			Throughput:  sum.completions / length,
+			Utilization: sum.areaBusy / length / ts.servers,
+			QueueLength: sum.areaQ / length,
+			InSystem:    (sum.areaQ + sum.areaBusy) / length,
+			MeanWait:    math.NaN(),
+			Blocking:    math.NaN(),
+		}
+		if sum.completions > 0 {
+			point.MeanWait = sum.waitSum / sum.completions
+		}
+		if sum.arrivals > 0 {
+			point.Blocking = sum.rejected / sum.arrivals
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// TimeSeries returns the windowed metrics up to time end, or nil when
+// time_series.window is not set.
+func (sc *EnhancedStatisticsCollector) TimeSeries(end float64) *models.TimeSeries {
+	if sc.series == nil {
+		return nil
+	}
+	return &models.TimeSeries{
+		Window: float64(sc.series.width) * sc.series.step,
+		Step:   sc.series.step,
+		Points: sc.series.points(end),
+	}
+}
+
+// writeTimeSeries exports the windows as CSV, one row per window.
+func writeTimeSeries(path string, series *models.TimeSeries) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create time series export: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	writer.WriteString("start,end,arrival_rate,throughput,utilization,queue_length,in_system,mean_wait,blocking\n")
+	for _, point := range series.Points {
+		values := []float64{point.Start, point.End, point.ArrivalRate, point.Throughput, point.Utilization,
+			point.QueueLength, point.InSystem, point.MeanWait, point.Blocking}
+		for i, value := range values {
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+			writer.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+		}
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write time series export: %v", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,106 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+func TestSlidingWindows(t *testing.T) {
+	// service takes 2: busy on [1, 5], [6, 8] and [9, 13], one customer
+	// queued on [2, 3] and [10.5, 11]; departures at 3, 5 (after waiting 1),
+	// 8, 11 and 13 (after waiting 0.5). Windows of 4 start every 2 time
+	// units until simulation_time.
+	config := &models.SimulationConfig{
+		SimulationTime: 100,
+		ServiceRate:    0.5,
+		MaxQueueSize:   -1,
+		StopCondition:  models.StopCondition{AutomaticMode: true, Type: stopTime},
+		TimeSeries:     models.TimeSeriesConfig{Window: 4, Step: 2},
+		Random: models.RandomConfig{
+			Seed:                1,
+			ArrivalDistribution: "trace",
+			ServiceDistribution: "constant",
+			ArrivalTimes:        []float64{1, 2, 6, 9, 10.5},
+		},
+	}
+	if err := ValidateTimeSeries(config); err != nil {
+		t.Fatal(err)
+	}
+	series := newSilentSimulator(config, 1, false).Run().TimeSeries
+
+	want := []models.TimeSeriesPoint{
+		{Start: 0, End: 4, ArrivalRate: 0.5, Throughput: 0.25, Utilization: 0.75, QueueLength: 0.25, InSystem: 1, MeanWait: 0, Blocking: 0},
+		{Start: 2, End: 6, ArrivalRate: 0.25, Throughput: 0.5, Utilization: 0.75, QueueLength: 0.25, InSystem: 1, MeanWait: 0.5, Blocking: 0},
+		{Start: 4, End: 8, ArrivalRate: 0.25, Throughput: 0.25, Utilization: 0.75, QueueLength: 0, InSystem: 0.75, MeanWait: 1, Blocking: 0},
+		{Start: 6, End: 10, ArrivalRate: 0.5, Throughput: 0.25, Utilization: 0.75, QueueLength: 0, InSystem: 0.75, MeanWait: 0, Blocking: 0},
+		{Start: 8, End: 12, ArrivalRate: 0.5, Throughput: 0.5, Utilization: 0.75, QueueLength: 0.125, InSystem: 0.875, MeanWait: 0, Blocking: 0},
+	}
+	if series.Window != 4 || series.Step != 2 || len(series.Points) != 49 {
+		t.Fatalf("%d windows of %g every %g, want 49 of 4 every 2", len(series.Points), series.Window, series.Step)
+	}
+	if last := series.Points[48]; last.Start != 96 || last.ArrivalRate != 0 || last.Utilization != 0 || !math.IsNaN(last.MeanWait) {
+		t.Errorf("last window %+v, want [96, 100) without traffic", *last)
+	}
+	for i, got := range series.Points[:len(want)] {
+		w := want[i]
+		pairs := [][2]float64{
+			{got.Start, w.Start}, {got.End, w.End}, {got.ArrivalRate, w.ArrivalRate}, {got.Throughput, w.Throughput},
+			{got.Utilization, w.Utilization}, {got.QueueLength, w.QueueLength}, {got.InSystem, w.InSystem},
+			{got.MeanWait, w.MeanWait}, {got.Blocking, w.Blocking},
+		}
+		for _, pair := range pairs {
+			if math.Abs(pair[0]-pair[1]) > 1e-12 {
+				t.Errorf("window %d: got %+v, want %+v", i, *got, w)
+				break
+			}
+		}
+	}
+}
+
+func TestSlidingWindowsBlockingAndEmpty(t *testing.T) {
+	// one rejected arrival at 0.5 and nothing else: the first window blocks
+	// every arrival; the later ones have no arrivals or departures, so their
+	// blocking and mean wait are undefined
+	config := &models.SimulationConfig{Servers: 1, TimeSeries: models.TimeSeriesConfig{Window: 3, Step: 1}}
+	ts := newTimeSeriesAccumulator(config)
+	ts.arrival(0.5, true)
+	ts.advance(0, 0, 0, 5)
+
+	if n := len(ts.points(4.5)); n != 2 {
+		t.Errorf("%d windows end by 4.5, want 2", n)
+	}
+	points := ts.points(5)
+	if len(points) != 3 {
+		t.Fatalf("%d windows end by 5, want 3", len(points))
+	}
+	if first := points[0]; first.ArrivalRate != 1.0/3 || first.Blocking != 1 || !math.IsNaN(first.MeanWait) {
+		t.Errorf("first window %+v, want arrival rate 1/3, blocking 1 and no mean wait", *first)
+	}
+	for _, point := range points[1:] {
+		if point.ArrivalRate != 0 || !math.IsNaN(point.Blocking) || !math.IsNaN(point.MeanWait) {
+			t.Errorf("window [%g, %g] %+v, want no traffic", point.Start, point.End, *point)
+		}
+	}
+}
+
+func TestValidateTimeSeries(t *testing.T) {
+	tests := []struct {
+		window, step float64
+		valid        bool
+	}{
+		{0, 0, true},
+		{10, 0, true},
+		{10, 2.5, true},
+		{10, 10, true},
+		{10, 3, false},
+		{10, 20, false},
+		{-1, 0, false},
+	}
+	for _, tt := range tests {
+		config := &models.SimulationConfig{TimeSeries: models.TimeSeriesConfig{Window: tt.window, Step: tt.step}}
+		if err := ValidateTimeSeries(config); (err == nil) != tt.valid {
+			t.Errorf("window %g, step %g: error %v, want valid %v", tt.window, tt.step, err, tt.valid)
+		}
+	}
+}
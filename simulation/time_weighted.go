@@ -0,0 +1,153 @@
+package simulation
+
+import "math"
+
+const (
+	maxSections          = 64
+	initialSectionLength = 1.0
+)
+
+// timeWeightedHistogram records how long an integer-valued state (such as
+// the queue length) spent at each level.
+type timeWeightedHistogram struct {
+	time  []float64
+	total float64
+}
+
+func (h *timeWeightedHistogram) add(level int, dt float64) {
+	for len(h.time) <= level {
+		h.time = append(h.time, 0)
+	}
+	h.time[level] += dt
+	h.total += dt
+}
+
+func (h *timeWeightedHistogram) merge(other *timeWeightedHistogram) {
+	for level, dt := range other.time {
+		h.add(level, dt)
+	}
+}
+
+// distribution returns the fraction of time spent at each level, P(X = n).
+func (h *timeWeightedHistogram) distribution() []float64 {
+	if h.total == 0 {
+		return nil
+	}
+	probabilities := make([]float64, len(h.time))
+	for level, dt := range h.time {
+		probabilities[level] = dt / h.total
+	}
+	return probabilities
+}
+
+// quantile returns the smallest level whose cumulative time fraction reaches p.
+func (h *timeWeightedHistogram) quantile(p float64) float64 {
+	if h.total == 0 {
+		return 0
+	}
+	cumulative := 0.0
+	for level, dt := range h.time {
+		cumulative += dt
+		if cumulative >= p*h.total {
+			return float64(level)
+		}
+	}
+	return float64(len(h.time) - 1)
+}
+
+// timeSections splits simulated time, or any other additive measure such as
+// a count of customers, into consecutive sections of equal length, each
+// summarised by a T. When maxSections is reached, neighbouring
+// sections are merged and the section length doubles, so memory stays
+// bounded however long the run is. The complete sections give approximately
+// independent replicates for confidence intervals (the sectioning method).
+type timeSections[T any] struct {
+	sections   []T
+	length     float64
+	used       float64
+	newSection func() T
+	merge      func(into, from T)
+}
+
+func newTimeSections[T any](newSection func() T, merge func(into, from T)) *timeSections[T] {
+	return &timeSections[T]{
+		sections:   []T{newSection()},
+		length:     initialSectionLength,
+		newSection: newSection,
+		merge:      merge,
+	}
+}
+
+// advance spreads dt over the sections, calling record with each section
+// and the part of dt that falls into it.
+func (ts *timeSections[T]) advance(dt float64, record func(section T, step float64)) {
+	for dt > 0 {
+		room := ts.length - ts.used
+		if room <= 0 {
+			ts.startSection()
+			continue
+		}
+		step := math.Min(dt, room)
+		record(ts.current(), step)
+		ts.used += step
+		dt -= step
+	}
+}
+
+// current returns the section that the present time falls into.
+func (ts *timeSections[T]) current() T {
+	return ts.sections[len(ts.sections)-1]
+}
+
+// startSection begins an empty section after the full current one. At
+// maxSections the neighbours are merged first; the merged sections are all
+// full at the doubled length, so the new section still starts empty.
+func (ts *timeSections[T]) startSection() {
+	if len(ts.sections) == maxSections {
+		merged := make([]T, 0, maxSections)
+		for i := 0; i < len(ts.sections); i += 2 {
+			ts.merge(ts.sections[i], ts.sections[i+1])
+			merged = append(merged, ts.sections[i])
+		}
+		ts.sections = merged
+		ts.length *= 2
+	}
+	ts.sections = append(ts.sections, ts.newSection())
+	ts.used = 0
+}
+
+// complete returns the sections that span a full section length.
+func (ts *timeSections[T]) complete() []T {
+	if ts.used < ts.length {
+		return ts.sections[:len(ts.sections)-1]
+	}
+	return ts.sections
+}
+
+// sectionedHistogram keeps an overall time-weighted histogram plus one per
+// section of simulated time.
+type sectionedHistogram struct {
+	overall  timeWeightedHistogram
+	sections *timeSections[*timeWeightedHistogram]
+}
+
+func newSectionedHistogram() *sectionedHistogram {
+	return &sectionedHistogram{
+		sections: newTimeSections(
+			func() *timeWeightedHistogram { return &timeWeightedHistogram{} },
+			(*timeWeightedHistogram).merge,
+		),
+	}
+}
+
+func (sh *sectionedHistogram) add(level int, dt float64) {
+	sh.overall.add(level, dt)
+	sh.sections.advance(dt, func(section *timeWeightedHistogram, step float64) {
+		section.add(level, step)
+	})
+}
+
+// completeSections returns the sections that span a full section length.
+func (sh *sectionedHistogram) completeSections() []*timeWeightedHistogram {
+	return sh.sections.complete()
+}
@@ -0,0 +1,115 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSectionedHistogramSectionsStayEqual(t *testing.T) {
+	tests := []struct {
+		name  string
+		total float64
+		step  float64
+	}{
+		{"one merge", 100 * initialSectionLength, 0.3},
+		{"several merges", 1000 * initialSectionLength, 0.7},
+		{"steps longer than a section", 5000 * initialSectionLength, 13.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh := newSectionedHistogram()
+			level := 0
+			for elapsed := 0.0; elapsed < tt.total; elapsed += tt.step {
+				sh.add(level, tt.step)
+				level = (level + 1) % 5
+			}
+
+			if sh.sections.length <= initialSectionLength {
+				t.Fatalf("section length %g, expected at least one merge", sh.sections.length)
+			}
+			if len(sh.sections.sections) > maxSections {
+				t.Fatalf("%d sections, more than %d", len(sh.sections.sections), maxSections)
+			}
+			complete := sh.completeSections()
+			covered := 0.0
+			for i, section := range complete {
+				if math.Abs(section.total-sh.sections.length) > 1e-9*sh.sections.length {
+					t.Errorf("section %d spans %g, want %g", i, section.total, sh.sections.length)
+				}
+				covered += section.total
+			}
+			if covered > sh.overall.total+1e-9 || sh.overall.total-covered >= sh.sections.length {
+				t.Errorf("complete sections cover %g of %g", covered, sh.overall.total)
+			}
+		})
+	}
+}
+
+func TestTimeWeightedHistogram(t *testing.T) {
+	// N(t) = 0 on [0, 1.5), 2 on [1.5, 2), 1 on [2, 4)
+	h := newSectionedHistogram()
+	h.add(0, 1.5)
+	h.add(2, 0.5)
+	h.add(1, 2)
+
+	want := []float64{0.375, 0.5, 0.125}
+	got := h.overall.distribution()
+	if len(got) != len(want) {
+		t.Fatalf("distribution %v, want %v", got, want)
+	}
+	for n := range want {
+		if math.Abs(got[n]-want[n]) > 1e-12 {
+			t.Errorf("P(N=%d) = %g, want %g", n, got[n], want[n])
+		}
+	}
+	for _, q := range []struct{ p, want float64 }{{0.375, 0}, {0.5, 1}, {0.875, 1}, {0.9, 2}} {
+		if got := h.overall.quantile(q.p); got != q.want {
+			t.Errorf("quantile(%g) = %g, want %g", q.p, got, q.want)
+		}
+	}
+
+	// sections of length 1: {0}, {0, 2}, {1}, {1}
+	sections := h.completeSections()
+	wantSections := [][]float64{{1}, {0.5, 0, 0.5}, {0, 1}, {0, 1}}
+	if len(sections) != len(wantSections) {
+		t.Fatalf("%d complete sections, want %d", len(sections), len(wantSections))
+	}
+	for i, section := range sections {
+		for n, dt := range wantSections[i] {
+			if math.Abs(section.time[n]-dt) > 1e-12 {
+				t.Errorf("section %d spent %g at level %d, want %g", i, section.time[n], n, dt)
+			}
+		}
+	}
+}
+
+func TestSystemSizeDistributionMatchesMM1(t *testing.T) {
+	// M/M/1 with rho = 0.5: P(N=n) = (1-rho) rho^n and P(Q=0) = 1 - rho^2
+	config := mm1TestConfig(0.5, 1, 20000)
+	metrics := newSilentSimulator(config, config.Random.Seed, false).Run().Metrics
+
+	system := metrics.SystemSizeDistribution
+	for n := 0; n < 4; n++ {
+		if want := 0.5 * math.Pow(0.5, float64(n)); math.Abs(system[n]-want) > 0.02 {
+			t.Errorf("P(N=%d) = %.4f, want %.4f", n, system[n], want)
+		}
+	}
+	if queue := metrics.QueueLengthDistribution; math.Abs(queue[0]-0.75) > 0.02 {
+		t.Errorf("P(Q=0) = %.4f, want 0.75", queue[0])
+	}
+
+	// the distributions are consistent with the time-average areas
+	mean := func(p []float64) float64 {
+		sum := 0.0
+		for n, pn := range p {
+			sum += float64(n) * pn
+		}
+		return sum
+	}
+	if got := mean(system); math.Abs(got-metrics.AverageInSystem) > 1e-9 {
+		t.Errorf("mean of P(N=n) is %g, average in system %g", got, metrics.AverageInSystem)
+	}
+	if got := mean(metrics.QueueLengthDistribution); math.Abs(got-metrics.AverageQueueLength) > 1e-9 {
+		t.Errorf("mean of P(Q=n) is %g, average queue length %g", got, metrics.AverageQueueLength)
+	}
+}
@@ -0,0 +1,242 @@
+package simulation
+
+import (
+	"bufio"
+	"des/models"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+const minTransientReplications = 2
+
+// transientSample is the state of one replication at one observation time.
+type transientSample struct {
+	queue       float64
+	inSystem    float64
+	utilization float64
+	wait        float64
+}
+
+// ValidateTransient checks the replication count, the initial state and the
+// observation times of a transient analysis.
+func ValidateTransient(config *models.SimulationConfig) error {
+	transient := config.Transient
+	if transient.Replications < minTransientReplications {
+		return fmt.Errorf("replications must be at least %d", minTransientReplications)
+	}
+	if transient.InitialCustomers < 0 {
+		return fmt.Errorf("initial_customers must be non-negative")
+	}
+	if capacity := serverCount(config) + config.MaxQueueSize; config.MaxQueueSize >= 0 && transient.InitialCustomers > capacity {
+		return fmt.Errorf("initial_customers %d exceeds the system capacity %d", transient.InitialCustomers, capacity)
+	}
+	if len(transient.Times) == 0 {
+		if transient.Step <= 0 || config.SimulationTime <= 0 {
+			return fmt.Errorf("set times, or a positive step and simulation_time")
+		}
+		return nil
+	}
+	for i, t := range transient.Times {
+		if t < 0 || (i > 0 && t <= transient.Times[i-1]) {
+			return fmt.Errorf("times must be non-negative and strictly increasing")
+		}
+	}
+	return nil
+}
+
+// transientTimes returns the configured observation times, or every step
+// from 0 up to simulation_time.
+func transientTimes(config *models.SimulationConfig) []float64 {
+	if len(config.Transient.Times) > 0 {
+		return config.Transient.Times
+	}
+	var times []float64
+	for i := 0; ; i++ {
+		t := float64(i) * config.Transient.Step
+		if t > config.SimulationTime+1e-9 {
+			return times
+		}
+		times = append(times, t)
+	}
+}
+
+// RunTransient runs transient.replications replications from the configured
+// initial state, without warm-up, and estimates the expected queue length,
+// number in system, utilization and wait at every observation time by the
+// ensemble average across replications with a Student-t interval. The
+// replications run on Workers(config) goroutines.
+func RunTransient(config *models.SimulationConfig, progress ProgressFunc) *models.TransientAnalysis {
+	times := transientTimes(config)
+	n := config.Transient.Replications
+	base := ResolveSeed(config.Random.Seed)
+
+	runConfig := *config
+	runConfig.SimulationTime = times[len(times)-1]
+	runConfig.StopCondition = models.StopCondition{Type: stopTime}
+	runConfig.MaxCustomers = 0
+	runConfig.Warmup = models.WarmupConfig{}
+
+	samples := make([][]transientSample, n)
+	runParallel(Workers(config), n, progress, func(i int) {
+		samples[i] = runTransientReplication(&runConfig, DeriveSeed(base, uint64(i)), times)
+	})
+
+	analysis := &models.TransientAnalysis{
+		Replications:     n,
+		InitialCustomers: config.Transient.InitialCustomers,
+		ConfidenceLevel:  confidenceLevel(config),
+	}
+	for j, t := range times {
+		column := func(value func(transientSample) float64) *models.EnsembleEstimate {
+			values := make([]float64, n)
+			for i := range samples {
+				values[i] = value(samples[i][j])
+			}
+			return ensembleEstimate(values, analysis.ConfidenceLevel)
+		}
+		analysis.Points = append(analysis.Points, &models.TransientPoint{
+			Time:        t,
+			QueueLength: column(func(s transientSample) float64 { return s.queue }),
+			InSystem:    column(func(s transientSample) float64 { return s.inSystem }),
+			Utilization: column(func(s transientSample) float64 { return s.utilization }),
+			Wait:        column(func(s transientSample) float64 { return s.wait }),
+		})
+	}
+	return analysis
+}
+
+// runTransientReplication preloads the system and records its state at each
+// time, after every event up to and including that time.
+func runTransientReplication(config *models.SimulationConfig, seed int64, times []float64) []transientSample {
+	sim := newSilentSimulator(config, seed, false)
+	sim.preload(config.Transient.InitialCustomers)
+
+	samples := make([]transientSample, len(times))
+	for i, t := range times {
+		for next := sim.events.PeekNextEvent(); next != nil && next.Timestamp <= t; next = sim.events.PeekNextEvent() {
+			sim.advance(sim.events.GetNextEvent())
+		}
+		samples[i] = sim.transientSample(t)
+	}
+	return samples
+}
+
+// preload puts k customers into the system at time 0, filling the servers
+// before the queue, as if they had all arrived at once.
+func (sim *DiscreteEventSimulator) preload(k int) {
+	for i := 0; i < k; i++ {
+		customer := &models.Customer{
+			ID:          sim.customerID,
+			ServiceTime: sim.events.GetServiceTime(),
+			Status:      models.CustomerWaiting,
+		}
+		sim.customerID++
+		sim.state.TotalCustomers++
+		if sim.state.BusyServers == 0 && len(sim.state.Queue) == 0 {
+			sim.stats.StartCycle(0)
+		}
+		if sim.state.BusyServers < serverCount(sim.config) {
+			if sim.state.BusyServers == 0 {
+				sim.stats.StartBusyPeriod(0)
+			}
+			sim.startService(customer)
+		} else {
+			sim.state.Queue = append(sim.state.Queue, customer)
+		}
+		sim.stats.RecordArrival(customer)
+	}
+}
+
+func (sim *DiscreteEventSimulator) transientSample(t float64) transientSample {
+	queue := len(sim.state.Queue)
+	return transientSample{
+		queue:       float64(queue),
+		inSystem:    float64(queue + sim.state.BusyServers),
+		utilization: float64(sim.state.BusyServers) / float64(serverCount(sim.config)),
+		wait:        sim.virtualWait(t),
+	}
+}
+
+// virtualWait is the wait of a customer who would arrive at time t: the
+// queued customers are assigned FIFO to the earliest free server, and the
+// new customer starts on the first server that is free after that. It is
+// NaN when the customer would be blocked.
+func (sim *DiscreteEventSimulator) virtualWait(t float64) float64 {
+	servers := serverCount(sim.config)
+	if sim.state.BusyServers >= servers && sim.config.MaxQueueSize >= 0 && len(sim.state.Queue) >= sim.config.MaxQueueSize {
+		return math.NaN()
+	}
+
+	free := make([]float64, 0, servers)
+	for _, event := range sim.events.eventList.events {
+		if event.Type == models.EventDeparture {
+			free = append(free, math.Max(0, event.Timestamp-t))
+		}
+	}
+	for len(free) < servers {
+		free = append(free, 0)
+	}
+	for _, customer := range sim.state.Queue {
+		sort.Float64s(free)
+		free[0] += customer.ServiceTime
+	}
+	sort.Float64s(free)
+	return free[0]
+}
+
+// ensembleEstimate summarises the replication values that are not NaN.
+func ensembleEstimate(values []float64, level float64) *models.EnsembleEstimate {
+	var valid []float64
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			valid = append(valid, v)
+		}
+	}
+	if len(valid) == 0 {
+		nan := math.NaN()
+		return &models.EnsembleEstimate{Mean: nan, StdDev: nan, Confidence: [2]float64{nan, nan}}
+	}
+	return &models.EnsembleEstimate{
+		Observations: len(valid),
+		Mean:         sampleMean(valid),
+		StdDev:       math.Sqrt(sampleVariance(valid)),
+		Confidence:   tConfidenceInterval(valid, level),
+	}
+}
+
+// WriteTransient exports the ensemble means and interval bounds as CSV, one
+// row per observation time, and records the file in the analysis.
+func WriteTransient(path string, analysis *models.TransientAnalysis) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create transient export: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	writer.WriteString("time")
+	for _, name := range []string{"queue_length", "in_system", "utilization", "wait"} {
+		writer.WriteString("," + name + "," + name + "_ci_low," + name + "_ci_high")
+	}
+	writer.WriteByte('\n')
+	for _, point := range analysis.Points {
+		writer.WriteString(strconv.FormatFloat(point.Time, 'g', -1, 64))
+		for _, estimate := range []*models.EnsembleEstimate{point.QueueLength, point.InSystem, point.Utilization, point.Wait} {
+			for _, value := range []float64{estimate.Mean, estimate.Confidence[0], estimate.Confidence[1]} {
+				writer.WriteByte(',')
+				writer.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+			}
+		}
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write transient export: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	analysis.ExportFile = path
+	return nil
+}
@@ -0,0 +1,98 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+	"testing"
+)
+
+// drainConfig preloads k customers and has no arrivals before time 1000.
+func drainConfig(k, servers int, distribution string, times []float64) *models.SimulationConfig {
+	return &models.SimulationConfig{
+		ServiceRate:  1,
+		MaxQueueSize: -1,
+		Servers:      servers,
+		Transient:    models.TransientConfig{Replications: 400, InitialCustomers: k, Times: times},
+		Random: models.RandomConfig{
+			Seed:                7,
+			ArrivalDistribution: "trace",
+			ServiceDistribution: distribution,
+			ArrivalTimes:        []float64{1000},
+		},
+	}
+}
+
+func TestTransientDeterministicDrain(t *testing.T) {
+	// three customers on one server with service 1 leave at 1, 2 and 3; a
+	// customer arriving at t waits for the remaining work
+	config := drainConfig(3, 1, "constant", []float64{0, 1.5, 2, 3})
+	if err := ValidateTransient(config); err != nil {
+		t.Fatal(err)
+	}
+	analysis := RunTransient(config, nil)
+
+	want := []struct{ queue, inSystem, utilization, wait float64 }{
+		{2, 3, 1, 3},
+		{1, 2, 1, 1.5},
+		{0, 1, 1, 1},
+		{0, 0, 0, 0},
+	}
+	for i, point := range analysis.Points {
+		for _, pair := range []struct {
+			name     string
+			estimate *models.EnsembleEstimate
+			want     float64
+		}{
+			{"queue length", point.QueueLength, want[i].queue},
+			{"number in system", point.InSystem, want[i].inSystem},
+			{"utilization", point.Utilization, want[i].utilization},
+			{"wait", point.Wait, want[i].wait},
+		} {
+			e := pair.estimate
+			if e.Observations != 400 || math.Abs(e.Mean-pair.want) > 1e-12 || e.Confidence[1]-e.Confidence[0] > 1e-12 {
+				t.Errorf("t=%g: %s %+v, want %g in every replication", point.Time, pair.name, *e, pair.want)
+			}
+		}
+	}
+}
+
+func TestTransientExponentialDrain(t *testing.T) {
+	// three customers on three servers with exponential service: each is
+	// still in service at t with probability e^-t, and by memorylessness a
+	// new arrival waits Exp(3) when all three are, so E[wait] = e^-3t / 3
+	times := []float64{0, 0.5, 1, 2}
+	analysis := RunTransient(drainConfig(3, 3, "exponential", times), nil)
+	for i, point := range analysis.Points {
+		p := math.Exp(-times[i])
+		for _, check := range []struct {
+			name     string
+			estimate *models.EnsembleEstimate
+			want     float64
+		}{
+			{"number in system", point.InSystem, 3 * p},
+			{"utilization", point.Utilization, p},
+			{"wait", point.Wait, p * p * p / 3},
+		} {
+			if c := check.estimate.Confidence; c[0] > check.want+1e-12 || c[1] < check.want-1e-12 {
+				t.Errorf("t=%g: %s interval [%.4f, %.4f] misses %.4f", point.Time, check.name, c[0], c[1], check.want)
+			}
+		}
+		if point.QueueLength.Mean != 0 {
+			t.Errorf("t=%g: queue length %g, want 0", point.Time, point.QueueLength.Mean)
+		}
+	}
+}
+
+func TestEnsembleEstimate(t *testing.T) {
+	// NaN marks a blocked customer and is left out: mean 2, s = 1,
+	// t_{0.975,2} = 4.302653
+	e := ensembleEstimate([]float64{1, 2, 3, math.NaN()}, 0.95)
+	halfWidth := 4.302653 / math.Sqrt(3)
+	if e.Observations != 3 || e.Mean != 2 || math.Abs(e.StdDev-1) > 1e-12 ||
+		math.Abs(e.Confidence[0]-(2-halfWidth)) > 1e-5 || math.Abs(e.Confidence[1]-(2+halfWidth)) > 1e-5 {
+		t.Errorf("estimate %+v, want 3 observations, mean 2, std dev 1, half-width %g", *e, halfWidth)
+	}
+	if e := ensembleEstimate([]float64{math.NaN()}, 0.95); e.Observations != 0 || !math.IsNaN(e.Mean) {
+		t.Errorf("estimate %+v of blocked customers only, want no observations", *e)
+	}
+}
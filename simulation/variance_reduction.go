@@ -0,0 +1,126 @@
+package simulation
+
+import (
+	"des/models"
+	"fmt"
+	"math"
+)
+
+const (
+	comparisonPairedT = "paired-t"
+	comparisonWelch   = "welch"
+)
+
+// ComparePaired runs configA and configB for a number of replication pairs
+// and estimates the mean difference of one metric. With common random
+// numbers both scenarios of a pair share a seed, so customer i sees the same
+// interarrival and service uniforms in both; with antithetic variates every
+// replication is the average of a U run and a 1-U run.
+//
+// With common random numbers the interval is a paired-t interval on the
+// differences; without, the two samples are independent and the interval is
+// Welch's.
+//
+// The variance reduction is measured on the paired differences: the observed
+// Var(A-B) is compared with Var(A)+Var(B), which is what independent sampling
+// would have produced from the same runs. The pairs run on Workers(configA)
+// goroutines.
+func ComparePaired(configA, configB *models.SimulationConfig, options models.VarianceReductionConfig, progress ProgressFunc) (*models.ScenarioComparison, error) {
+	metric, err := LookupMetric(configA, options.Metric)
+	if err != nil {
+		return nil, err
+	}
+	pairs := options.Pairs
+	if pairs < 2 {
+		return nil, fmt.Errorf("variance reduction needs at least 2 pairs, got %d", pairs)
+	}
+
+	configA = ResolveWarmup(configA)
+	configB = ResolveWarmup(configB)
+	base := ResolveSeed(configA.Random.Seed)
+	comparison := &models.ScenarioComparison{
+		Metric:              metric.Name,
+		Pairs:               pairs,
+		CommonRandomNumbers: options.CommonRandomNumbers,
+		Antithetic:          options.Antithetic,
+		ValuesA:             make([]float64, pairs),
+		ValuesB:             make([]float64, pairs),
+	}
+
+	runsA := make([][]float64, pairs)
+	runsB := make([][]float64, pairs)
+	runParallel(Workers(configA), pairs, progress, func(i int) {
+		seedA := DeriveSeed(base, uint64(i))
+		seedB := seedA
+		if !options.CommonRandomNumbers {
+			seedB = DeriveSeed(base, uint64(pairs+i))
+		}
+		comparison.ValuesA[i], runsA[i] = runScenario(configA, seedA, options.Antithetic, metric)
+		comparison.ValuesB[i], runsB[i] = runScenario(configB, seedB, options.Antithetic, metric)
+	})
+
+	var singlesA, singlesB []float64
+	differences := make([]float64, pairs)
+	for i := range differences {
+		singlesA = append(singlesA, runsA[i]...)
+		singlesB = append(singlesB, runsB[i]...)
+		differences[i] = comparison.ValuesA[i] - comparison.ValuesB[i]
+	}
+
+	comparison.MeanA = sampleMean(comparison.ValuesA)
+	comparison.MeanB = sampleMean(comparison.ValuesB)
+	comparison.MeanDifference = sampleMean(differences)
+	comparison.ConfidenceLevel = confidenceLevel(configA)
+	if options.CommonRandomNumbers {
+		comparison.Method = comparisonPairedT
+		comparison.Freedom = float64(pairs - 1)
+		comparison.DifferenceConfidence = tConfidenceInterval(differences, comparison.ConfidenceLevel)
+	} else {
+		comparison.Method = comparisonWelch
+		comparison.Freedom, comparison.DifferenceConfidence = welchInterval(comparison.ValuesA, comparison.ValuesB, comparison.ConfidenceLevel)
+	}
+	comparison.DifferenceVariance = sampleVariance(differences)
+	comparison.IndependentVariance = sampleVariance(comparison.ValuesA) + sampleVariance(comparison.ValuesB)
+	comparison.VarianceReduction = varianceReduction(comparison.DifferenceVariance, comparison.IndependentVariance)
+
+	if options.Antithetic {
+		comparison.AntitheticReductionA = varianceReduction(sampleVariance(comparison.ValuesA), sampleVariance(singlesA)/2)
+		comparison.AntitheticReductionB = varianceReduction(sampleVariance(comparison.ValuesB), sampleVariance(singlesB)/2)
+	}
+
+	return comparison, nil
+}
+
+// welchInterval returns the Welch-Satterthwaite degrees of freedom and the
+// interval for mean(a) - mean(b) from independent samples with unequal
+// variances.
+func welchInterval(a, b []float64, level float64) (float64, [2]float64) {
+	difference := sampleMean(a) - sampleMean(b)
+	va := sampleVariance(a) / float64(len(a))
+	vb := sampleVariance(b) / float64(len(b))
+	if va+vb == 0 {
+		return math.NaN(), [2]float64{difference, difference}
+	}
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	halfWidth := StudentTQuantile(1-(1-level)/2, df) * math.Sqrt(va+vb)
+	return df, [2]float64{difference - halfWidth, difference + halfWidth}
+}
+
+// runScenario returns the replication value of metric together with the
+// individual run values it was built from.
+func runScenario(config *models.SimulationConfig, seed int64, antithetic bool, metric MetricDefinition) (float64, []float64) {
+	value := metric.Value(RunSilent(config, seed, false).Metrics)
+	if !antithetic {
+		return value, []float64{value}
+	}
+	mirrored := metric.Value(RunSilent(config, seed, true).Metrics)
+	return (value + mirrored) / 2, []float64{value, mirrored}
+}
+
+// varianceReduction returns the fraction of reference variance removed.
+func varianceReduction(achieved, reference float64) float64 {
+	if reference <= 0 {
+		return 0
+	}
+	return 1 - achieved/reference
+}
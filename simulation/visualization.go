@@ -4,6 +4,7 @@ import (
 	"des/logging"
 	"des/models"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 )
@@ -26,8 +27,13 @@ func (tv *TerminalVisualizer) ClearScreen() {
 }
 
 func (tv *TerminalVisualizer) DisplayHeader(config *models.SimulationConfig) {
-	header := fmt.Sprintf("%s\nDISCRETE EVENT SIMULATION - SINGLE SERVER QUEUEING SYSTEM\n%s\nConfiguration: Arrival Rate=%.2f, Service Rate=%.2f, Max Queue=%d\n%s\n",
+	title := "SINGLE SERVER QUEUEING SYSTEM"
+	if serverCount(config) > 1 {
+		title = fmt.Sprintf("%d-SERVER QUEUEING SYSTEM", serverCount(config))
+	}
+	header := fmt.Sprintf("%s\nDISCRETE EVENT SIMULATION - %s\n%s\nConfiguration: Arrival Rate=%.2f, Service Rate=%.2f, Max Queue=%d\n%s\n",
 		strings.Repeat("=", 80),
+		title,
 		strings.Repeat("=", 80),
 		config.ArrivalRate, config.ServiceRate, config.MaxQueueSize,
 		strings.Repeat("-", 80))
@@ -50,6 +56,9 @@ func (tv *TerminalVisualizer) DisplayState(state *models.SystemState, nextEvent
 	if state.ServerBusy {
 		serverStatus = "BUSY"
 	}
+	if serverCount(config) > 1 {
+		serverStatus = fmt.Sprintf("%d/%d", state.BusyServers, serverCount(config))
+	}
 	stateStr += fmt.Sprintf("SERVER STATUS: %-6s    CUSTOMERS SERVED: %6d\n", serverStatus, state.CustomersServed)
 	stateStr += fmt.Sprintf("QUEUE LENGTH: %3d/%3d    REJECTED CUSTOMERS: %4d\n",
 		len(state.Queue), config.MaxQueueSize, state.RejectedCustomers)
@@ -98,7 +107,7 @@ func (tv *TerminalVisualizer) DisplayState(state *models.SystemState, nextEvent
 
 	serverUtil := 0.0
 	if state.Clock > 0 {
-		serverUtil = (state.AreaUnderB / state.Clock) * 100
+		serverUtil = (state.AreaUnderB / state.Clock / float64(serverCount(config))) * 100
 		if serverUtil < 0 {
 			serverUtil = 0
 		}
@@ -151,25 +160,69 @@ func (tv *TerminalVisualizer) DisplayResults(results *models.SimulationResults,
 	resultsStr += fmt.Sprintf("  Wait Time Variance:           %12.4f\n", metrics.WaitTimeVariance)
 	resultsStr += fmt.Sprintf("  System Time Variance:         %12.4f\n", metrics.SystemTimeVariance)
 
-	if len(metrics.WaitTimePercentiles) > 0 {
-		resultsStr += fmt.Sprintf("\nWAIT TIME PERCENTILES:\n")
-		resultsStr += fmt.Sprintf("  50th (Median):              %12.4f\n", metrics.WaitTimePercentiles["50th"])
-		resultsStr += fmt.Sprintf("  75th:                       %12.4f\n", metrics.WaitTimePercentiles["75th"])
-		resultsStr += fmt.Sprintf("  90th:                       %12.4f\n", metrics.WaitTimePercentiles["90th"])
-		resultsStr += fmt.Sprintf("  95th:                       %12.4f\n", metrics.WaitTimePercentiles["95th"])
+	if len(metrics.WaitTimeQuantiles) > 0 {
+		resultsStr += tv.formatQuantiles(metrics, config)
+	}
+	if len(metrics.SystemSizeDistribution) > 0 {
+		resultsStr += tv.formatStateDistribution(metrics.SystemSizeDistribution, metrics.QueueLengthDistribution)
 	}
 
-	resultsStr += fmt.Sprintf("\n95%% CONFIDENCE INTERVALS:\n")
-	resultsStr += fmt.Sprintf("  Wait Time:        [%8.4f, %8.4f]\n",
-		metrics.WaitTimeConfidence[0], metrics.WaitTimeConfidence[1])
-	resultsStr += fmt.Sprintf("  System Time:      [%8.4f, %8.4f]\n",
-		metrics.SystemTimeConfidence[0], metrics.SystemTimeConfidence[1])
+	resultsStr += fmt.Sprintf("\n%.0f%% CONFIDENCE INTERVALS:\n", metrics.ConfidenceLevel*100)
+	resultsStr += tv.formatMeanInterval("Wait Time:", metrics.WaitTimeConfidence, metrics.WaitTimeBatchMeans)
+	resultsStr += tv.formatMeanInterval("System Time:", metrics.SystemTimeConfidence, metrics.SystemTimeBatchMeans)
+
+	if metrics.WaitTimeControlVariate != nil && metrics.SystemTimeControlVariate != nil {
+		resultsStr += fmt.Sprintf("\nCONTROL-VARIATE ESTIMATES (%d batches, controls: %s):\n",
+			metrics.WaitTimeControlVariate.Batches, strings.Join(metrics.WaitTimeControlVariate.Controls, ", "))
+		resultsStr += fmt.Sprintf("  %-12s %10s %22s %10s %22s %10s\n", "", "Raw", "Raw CI", "Adjusted", "Adjusted CI", "Var. Red.")
+		resultsStr += tv.formatControlVariateRow("Wait Time", metrics.AverageWaitTime, metrics.WaitTimeConfidence, metrics.WaitTimeControlVariate)
+		resultsStr += tv.formatControlVariateRow("System Time", metrics.AverageSystemTime, metrics.SystemTimeConfidence, metrics.SystemTimeControlVariate)
+	}
+
+	if metrics.Regenerative != nil {
+		resultsStr += tv.formatRegenerative(metrics.Regenerative, metrics.ConfidenceLevel)
+	}
+	if results.Warmup != nil {
+		resultsStr += tv.formatWelchPlot(results.Warmup)
+	}
+	if metrics.WarmupTime > 0 {
+		resultsStr += "\nWARM-UP DELETION:\n"
+		resultsStr += fmt.Sprintf("  Statistics Reset at Time:     %12.4f\n", metrics.WarmupTime)
+		resultsStr += fmt.Sprintf("  Customers Deleted:            %12d\n", metrics.WarmupCustomers)
+	}
+
+	if results.Precision != nil {
+		resultsStr += tv.formatPrecision(results.Precision)
+	}
+	if results.Theory != nil {
+		resultsStr += tv.formatTheory(results.Theory)
+	}
+	if len(metrics.SLA) > 0 {
+		resultsStr += tv.formatSLA(metrics.SLA, metrics.ConfidenceLevel)
+	}
+	if metrics.Cost != nil {
+		resultsStr += tv.formatCost(metrics.Cost, metrics.ConfidenceLevel)
+	}
+	if results.TimeSeries != nil && len(results.TimeSeries.Points) > 0 {
+		resultsStr += tv.formatTimeSeries(results.TimeSeries)
+	}
+	if metrics.BusyPeriods != nil && metrics.BusyPeriods.Busy != nil {
+		resultsStr += tv.formatBusyPeriods(metrics.BusyPeriods, metrics.ConfidenceLevel)
+	}
+	if metrics.DepartureProcess != nil {
+		resultsStr += tv.formatDepartureProcess(metrics.DepartureProcess)
+	}
+	if len(metrics.ConsistencyChecks) > 0 {
+		resultsStr += tv.formatConsistency(metrics.ConsistencyChecks, metrics.ConsistencyNote)
+	}
+	resultsStr += tv.formatWarnings(results.Warnings)
 
 	resultsStr += fmt.Sprintf("\nSIMULATION SUMMARY:\n")
 	resultsStr += fmt.Sprintf("  Total Simulation Time:        %12.2f time units\n", state.Clock)
 	resultsStr += fmt.Sprintf("  Total Customers Arrived:      %12d\n", state.TotalCustomers)
 	resultsStr += fmt.Sprintf("  Total Customers Served:       %12d\n", state.CustomersServed)
 	resultsStr += fmt.Sprintf("  Total Events Processed:       %12d\n", state.EventsProcessed)
+	resultsStr += fmt.Sprintf("  Stop Reason:                  %12s\n", results.StopReason)
 	resultsStr += fmt.Sprintf("  Area under Q(t):              %12.4f\n", state.AreaUnderQ)
 	resultsStr += fmt.Sprintf("  Area under B(t):              %12.4f\n", state.AreaUnderB)
 	resultsStr += fmt.Sprintf("  Real Execution Time:          %12v\n", results.Runtime)
@@ -183,6 +236,429 @@ func (tv *TerminalVisualizer) DisplayResults(results *models.SimulationResults,
 	}
 }
 
+// replicationColumns are the metrics shown per replication; the summary
+// table below them covers every metric.
+var replicationColumns = []struct{ name, header string }{
+	{"average_wait_time", "Wait"},
+	{"average_system_time", "System"},
+	{"average_queue_length", "Queue Len"},
+	{"server_utilization", "Util"},
+	{"throughput", "Throughput"},
+	{"blocking_probability", "Blocking"},
+}
+
+func (tv *TerminalVisualizer) DisplayReplications(summary *models.ReplicationSummary) {
+	resultsStr := fmt.Sprintf("\n%s\nINDEPENDENT REPLICATIONS (%d runs)\n%s\n",
+		strings.Repeat("=", 80), summary.Replications,
+		strings.Repeat("=", 80))
+
+	var columns []*models.MetricSummary
+	resultsStr += fmt.Sprintf("  %-5s", "Rep")
+	for _, column := range replicationColumns {
+		if metric := FindMetric(summary, column.name); metric != nil {
+			columns = append(columns, metric)
+			resultsStr += fmt.Sprintf(" %11s", column.header)
+		}
+	}
+	resultsStr += "\n"
+	for i := 0; i < summary.Replications; i++ {
+		resultsStr += fmt.Sprintf("  %-5d", i+1)
+		for _, metric := range columns {
+			resultsStr += fmt.Sprintf(" %11.4f", metric.Values[i])
+		}
+		resultsStr += "\n"
+	}
+
+	resultsStr += fmt.Sprintf("\nSUMMARY (%.0f%% t-intervals):\n", summary.ConfidenceLevel*100)
+	width := 22
+	for _, metric := range summary.Metrics {
+		width = max(width, len(metric.Label))
+	}
+	resultsStr += fmt.Sprintf("  %-*s %12s %12s %12s %12s\n", width, "Metric", "Mean", "Std Dev", "CI Low", "CI High")
+	for _, metric := range summary.Metrics {
+		resultsStr += fmt.Sprintf("  %-*s %12.4f %12.4f %12.4f %12.4f\n",
+			width, metric.Label, metric.Mean, metric.StdDev, metric.Confidence[0], metric.Confidence[1])
+	}
+	if summary.Precision != nil {
+		resultsStr += tv.formatPrecision(summary.Precision)
+	}
+	if summary.Theory != nil {
+		resultsStr += tv.formatTheory(summary.Theory)
+	}
+	if len(summary.SLA) > 0 {
+		resultsStr += tv.formatSLA(summary.SLA, summary.ConfidenceLevel)
+	}
+	resultsStr += tv.formatWarnings(summary.Warnings)
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) formatRegenerative(analysis *models.RegenerativeAnalysis, level float64) string {
+	regenStr := fmt.Sprintf("\nREGENERATIVE ANALYSIS (%d cycles):\n", analysis.Cycles)
+	regenStr += fmt.Sprintf("  Cycle Length Mean:            %12.4f (std dev %.4f)\n", analysis.CycleLengthMean, analysis.CycleLengthStdDev)
+	regenStr += fmt.Sprintf("  Cycle Length Min / Max:       %12.4f / %.4f\n", analysis.CycleLengthMin, analysis.CycleLengthMax)
+	regenStr += fmt.Sprintf("  Cycle Length 50th/90th/99th:  %12.4f / %.4f / %.4f\n",
+		analysis.CycleLengthPercentiles["50th"], analysis.CycleLengthPercentiles["90th"], analysis.CycleLengthPercentiles["99th"])
+	regenStr += fmt.Sprintf("  Customers per Cycle:          %12.4f\n", analysis.CustomersPerCycle)
+	regenStr += tv.formatHistogram(analysis.CycleLengthHistogram, 40)
+
+	regenStr += fmt.Sprintf("  %-22s %12s %24s\n", "Ratio Estimator", "Estimate", fmt.Sprintf("%.0f%% CI", level*100))
+	for _, estimate := range analysis.Estimates {
+		regenStr += fmt.Sprintf("  %-22s %12.4f   [%9.4f, %9.4f]\n",
+			estimate.Metric, estimate.Estimate, estimate.Confidence[0], estimate.Confidence[1])
+	}
+	return regenStr
+}
+
+// formatHistogram draws one bar per bin, scaled so the fullest bin spans width
+// characters.
+func (tv *TerminalVisualizer) formatHistogram(bins []models.HistogramBin, width int) string {
+	maxCount := 0
+	for _, bin := range bins {
+		if bin.Count > maxCount {
+			maxCount = bin.Count
+		}
+	}
+	if maxCount == 0 {
+		return ""
+	}
+	histStr := ""
+	for _, bin := range bins {
+		bar := strings.Repeat("#", bin.Count*width/maxCount)
+		histStr += fmt.Sprintf("  [%9.3f, %9.3f) %-*s %d\n", bin.Lower, bin.Upper, width, bar, bin.Count)
+	}
+	return histStr
+}
+
+// formatQuantiles prints one row per quantile level with the estimate and
+// interval for wait time, system time and queue length.
+func (tv *TerminalVisualizer) formatQuantiles(metrics *models.ComprehensiveMetrics, config *models.SimulationConfig) string {
+	method := config.Quantiles.CIMethod
+	if method == "" {
+		method = "order_statistics"
+	}
+	out := fmt.Sprintf("\nQUANTILES (%.0f%% CI, %s; queue length by sectioning):\n", metrics.ConfidenceLevel*100, method)
+	out += fmt.Sprintf("  %-8s %30s %30s %30s\n", "", "Wait Time", "System Time", "Queue Length")
+	for i, wait := range metrics.WaitTimeQuantiles {
+		out += fmt.Sprintf("  %-8s %s", wait.Key, tv.formatQuantile(wait))
+		if i < len(metrics.SystemTimeQuantiles) {
+			out += " " + tv.formatQuantile(metrics.SystemTimeQuantiles[i])
+		}
+		if i < len(metrics.QueueLengthQuantiles) {
+			out += " " + tv.formatQuantile(metrics.QueueLengthQuantiles[i])
+		}
+		out += "\n"
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatQuantile(q *models.QuantileEstimate) string {
+	return fmt.Sprintf("%8.3f [%8.3f, %8.3f]", q.Estimate, q.Confidence[0], q.Confidence[1])
+}
+
+// formatStateDistribution prints P(N=n) and P(Q=n) with a bar chart of
+// P(N=n). Rows stop once less than distributionTail of the time remains
+// above n; the remainder is summed into a final row.
+func (tv *TerminalVisualizer) formatStateDistribution(system, queue []float64) string {
+	const (
+		distributionTail = 0.001
+		barWidth         = 40
+	)
+	maxProbability := 0.0
+	for _, p := range system {
+		maxProbability = math.Max(maxProbability, p)
+	}
+
+	out := "\nTIME-WEIGHTED STATE DISTRIBUTION:\n"
+	out += fmt.Sprintf("  %6s %10s %10s %10s  %s\n", "n", "P(N=n)", "P(N<=n)", "P(Q=n)", "P(N=n)")
+	cumulative, queueCumulative := 0.0, 0.0
+	for n, p := range system {
+		if n > 0 && 1-cumulative < distributionTail {
+			out += fmt.Sprintf("  %6s %10.6f %10.6f %10.6f\n", fmt.Sprintf(">=%d", n), 1-cumulative, 1.0, math.Max(0, 1-queueCumulative))
+			break
+		}
+		cumulative += p
+		queueProbability := 0.0
+		if n < len(queue) {
+			queueProbability = queue[n]
+		}
+		queueCumulative += queueProbability
+		bar := strings.Repeat("#", int(p/maxProbability*barWidth))
+		out += fmt.Sprintf("  %6d %10.6f %10.6f %10.6f  %s\n", n, p, cumulative, queueProbability, bar)
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatBusyPeriods(analysis *models.BusyPeriodAnalysis, level float64) string {
+	out := fmt.Sprintf("\nBUSY AND IDLE PERIODS (%.0f%% CI):\n", level*100)
+	out += fmt.Sprintf("  %-20s %8s %10s %10s %22s %10s %10s\n", "", "Count", "Mean", "Std Dev", "CI", "Min", "Max")
+	rows := []struct {
+		label string
+		stats *models.PeriodStatistics
+	}{
+		{"Busy Period", analysis.Busy},
+		{"Idle Period", analysis.Idle},
+		{"Customers per Busy", analysis.CustomersPerBusyPeriod},
+	}
+	for _, row := range rows {
+		if row.stats == nil {
+			continue
+		}
+		out += fmt.Sprintf("  %-20s %8d %10.4f %10.4f   [%8.4f, %8.4f] %10.4f %10.4f\n",
+			row.label, row.stats.Count, row.stats.Mean, row.stats.StdDev,
+			row.stats.Confidence[0], row.stats.Confidence[1], row.stats.Min, row.stats.Max)
+	}
+	if len(analysis.Busy.Histogram) > 0 {
+		out += "  Busy period length:\n"
+		out += tv.formatHistogram(analysis.Busy.Histogram, 40)
+	}
+	if analysis.Idle != nil && len(analysis.Idle.Histogram) > 0 {
+		out += "  Idle period length:\n"
+		out += tv.formatHistogram(analysis.Idle.Histogram, 40)
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatDepartureProcess(process *models.DepartureProcess) string {
+	out := "\nDEPARTURE PROCESS:\n"
+	out += fmt.Sprintf("  Inter-departure Times:        %12d\n", process.Count)
+	out += fmt.Sprintf("  Departure Rate:               %12.4f customers/time unit\n", process.Rate)
+	out += fmt.Sprintf("  Mean Inter-departure Time:    %12.4f\n", process.MeanInterDeparture)
+	out += fmt.Sprintf("  Inter-departure Variance:     %12.4f\n", process.InterDepartureVariance)
+	out += fmt.Sprintf("  SCV:                          %12.4f\n", process.SCV)
+	for k, r := range process.Autocorrelations {
+		out += fmt.Sprintf("  Lag-%-2d Autocorrelation:       %12.4f\n", k+1, r)
+	}
+	if process.ExportFile != "" {
+		out += fmt.Sprintf("  Departure times written to %s\n", process.ExportFile)
+	}
+	return out
+}
+
+const maxTimeSeriesRows = 20
+
+// formatTimeSeries prints at most maxTimeSeriesRows evenly spaced windows
+// followed by the windows with the peak queue length, utilization and wait.
+func (tv *TerminalVisualizer) formatTimeSeries(series *models.TimeSeries) string {
+	points := series.Points
+	out := fmt.Sprintf("\nTIME SERIES (window %g, step %g, %d windows):\n", series.Window, series.Step, len(points))
+	out += fmt.Sprintf("  %10s %10s %9s %9s %9s %9s %9s %9s\n", "Start", "End", "Arr Rate", "Util", "Queue", "System", "Wait", "Blocking")
+	stride := (len(points) + maxTimeSeriesRows - 1) / maxTimeSeriesRows
+	for i := 0; i < len(points); i += stride {
+		out += tv.formatTimeSeriesPoint(points[i])
+	}
+
+	peakQueue, peakUtil, peakWait := points[0], points[0], points[0]
+	for _, point := range points {
+		if point.QueueLength > peakQueue.QueueLength {
+			peakQueue = point
+		}
+		if point.Utilization > peakUtil.Utilization {
+			peakUtil = point
+		}
+		if !math.IsNaN(point.MeanWait) && (math.IsNaN(peakWait.MeanWait) || point.MeanWait > peakWait.MeanWait) {
+			peakWait = point
+		}
+	}
+	out += fmt.Sprintf("  Peak Queue Length:            %12.4f in [%g, %g)\n", peakQueue.QueueLength, peakQueue.Start, peakQueue.End)
+	out += fmt.Sprintf("  Peak Utilization:             %12.4f %% in [%g, %g)\n", peakUtil.Utilization*100, peakUtil.Start, peakUtil.End)
+	out += fmt.Sprintf("  Peak Mean Wait:               %12.4f in [%g, %g)\n", peakWait.MeanWait, peakWait.Start, peakWait.End)
+	if series.ExportFile != "" {
+		out += fmt.Sprintf("  Time series written to %s\n", series.ExportFile)
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatTimeSeriesPoint(point *models.TimeSeriesPoint) string {
+	return fmt.Sprintf("  %10.2f %10.2f %9.4f %9.4f %9.4f %9.4f %9.4f %9.4f\n", point.Start, point.End, point.ArrivalRate,
+		point.Utilization, point.QueueLength, point.InSystem, point.MeanWait, point.Blocking)
+}
+
+func (tv *TerminalVisualizer) formatCost(cost *models.CostAnalysis, level float64) string {
+	out := fmt.Sprintf("\nCOST ANALYSIS (%.0f%% CI):\n", level*100)
+	out += fmt.Sprintf("  Waiting Cost:                 %12.4f\n", cost.Waiting)
+	out += fmt.Sprintf("  Holding Cost:                 %12.4f\n", cost.Holding)
+	out += fmt.Sprintf("  Staffing Cost:                %12.4f\n", cost.Staffing)
+	out += fmt.Sprintf("  Service Cost:                 %12.4f\n", cost.Service)
+	out += fmt.Sprintf("  Rejection Penalties:          %12.4f\n", cost.Rejection)
+	out += fmt.Sprintf("  Revenue (credited):           %12.4f\n", cost.Revenue)
+	out += fmt.Sprintf("  Net Cost:                     %12.4f\n", cost.Total)
+	out += fmt.Sprintf("  Cost per Time Unit:           %12.4f [%.4f, %.4f]\n", cost.Rate, cost.RateConfidence[0], cost.RateConfidence[1])
+	out += fmt.Sprintf("  Cost per Customer:            %12.4f [%.4f, %.4f]\n", cost.PerCustomer, cost.PerCustomerConfidence[0], cost.PerCustomerConfidence[1])
+	return out
+}
+
+// formatSLA prints each service-level target with the attained fraction, its
+// interval and the verdict; "?" marks a verdict whose interval contains the
+// target.
+func (tv *TerminalVisualizer) formatSLA(results []*models.SLAResult, level float64) string {
+	out := fmt.Sprintf("\nSERVICE LEVELS (%.0f%% CI):\n", level*100)
+	out += fmt.Sprintf("  %-30s %9s %9s %22s %7s\n", "Target", "Goal", "Attained", "CI", "Status")
+	for _, result := range results {
+		goal := fmt.Sprintf(">=%.1f%%", result.Target*100)
+		if result.Type == slaExceeds {
+			goal = fmt.Sprintf("<=%.1f%%", result.Target*100)
+		}
+		if math.IsNaN(result.Attained) {
+			out += fmt.Sprintf("  %-30s %9s %9s %22s %7s\n", result.Name, goal, "-", "-", "-")
+			continue
+		}
+		status := "FAIL"
+		if result.Passed {
+			status = "PASS"
+		}
+		if !result.Conclusive {
+			status += "?"
+		}
+		out += fmt.Sprintf("  %-30s %9s %8.2f%% [%8.2f%%, %8.2f%%] %7s\n", result.Name, goal, result.Attained*100,
+			result.Confidence[0]*100, result.Confidence[1]*100, status)
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatConsistency(checks []*models.ConsistencyCheck, note string) string {
+	out := "\nCONSISTENCY CHECKS:\n"
+	out += fmt.Sprintf("  %-38s %12s %12s %9s %6s\n", "Check", "Expected", "Observed", "Rel. Err", "OK")
+	for _, check := range checks {
+		status := "yes"
+		switch {
+		case check.Skipped:
+			status = "skip"
+		case !check.Passed:
+			status = "NO"
+		}
+		out += fmt.Sprintf("  %-38s %12.4f %12.4f %+8.2f%% %6s\n", check.Name, check.Expected, check.Observed, check.RelativeError*100, status)
+	}
+	if note != "" {
+		out += fmt.Sprintf("  skip: %s\n", note)
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatWarnings(warnings []string) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	out := "\nWARNINGS:\n"
+	for _, warning := range warnings {
+		out += fmt.Sprintf("  ! %s\n", warning)
+	}
+	return out
+}
+
+// formatTheory prints the simulated metrics next to the analytic model with
+// the relative error and whether the analytic value lies inside the CI.
+func (tv *TerminalVisualizer) formatTheory(validation *models.TheoryValidation) string {
+	if validation.Model == nil {
+		return fmt.Sprintf("\nANALYTIC VALIDATION: not available (%s)\n", validation.Unavailable)
+	}
+	out := fmt.Sprintf("\nANALYTIC VALIDATION (%s, %.0f%% CI):\n", validation.Model.Name, validation.ConfidenceLevel*100)
+	out += fmt.Sprintf("  %-22s %11s %11s %9s %22s %6s\n", "Metric", "Analytic", "Simulated", "Rel. Err", "CI", "In CI")
+	for _, c := range validation.Comparisons {
+		relative := "-"
+		if !math.IsNaN(c.RelativeError) {
+			relative = fmt.Sprintf("%+.2f%%", c.RelativeError*100)
+		}
+		interval, inside := "-", "-"
+		if c.HasConfidence {
+			interval = fmt.Sprintf("[%9.4f, %9.4f]", c.Confidence[0], c.Confidence[1])
+			inside = "no"
+			if c.WithinCI {
+				inside = "yes"
+			}
+		}
+		out += fmt.Sprintf("  %-22s %11.4f %11.4f %9s %22s %6s\n", c.Label, c.Analytic, c.Simulated, relative, interval, inside)
+	}
+	return out
+}
+
+func (tv *TerminalVisualizer) formatPrecision(report *models.PrecisionReport) string {
+	status := "TARGET MET"
+	if !report.Converged {
+		status = "BUDGET EXHAUSTED (" + report.Budget + ")"
+	}
+	precisionStr := fmt.Sprintf("\nACHIEVED PRECISION: %s\n", status)
+	precisionStr += fmt.Sprintf("  Target: relative %.4f, absolute %.4f\n", report.Relative, report.Absolute)
+	precisionStr += fmt.Sprintf("  %-22s %12s %12s %12s %6s\n", "Metric", "Estimate", "Half-width", "Relative", "Met")
+	for _, result := range report.Metrics {
+		precisionStr += fmt.Sprintf("  %-22s %12.4f %12.4f %12.4f %6v\n",
+			result.Metric, result.Estimate, result.HalfWidth, result.RelativeHalfWidth, result.Met)
+	}
+	return precisionStr
+}
+
+// formatMeanInterval prints a confidence interval with the batches it came
+// from; a NaN interval means the run was too short for batch means.
+func (tv *TerminalVisualizer) formatMeanInterval(label string, confidence [2]float64, batchMeans *models.BatchMeansResult) string {
+	if math.IsNaN(confidence[0]) {
+		return fmt.Sprintf("  %-17s unavailable (too few customers for batch means)\n", label)
+	}
+	if batchMeans == nil {
+		return fmt.Sprintf("  %-17s [%8.4f, %8.4f]\n", label, confidence[0], confidence[1])
+	}
+	return fmt.Sprintf("  %-17s [%8.4f, %8.4f]  %s: %d x %d, df %.1f, lag-1 r = %.3f\n",
+		label, confidence[0], confidence[1], batchMeans.Method, batchMeans.Batches, batchMeans.BatchSize,
+		batchMeans.DegreesOfFreedom, batchMeans.Lag1Autocorrelation)
+}
+
+func (tv *TerminalVisualizer) formatControlVariateRow(label string, raw float64, rawCI [2]float64, cv *models.ControlVariateEstimate) string {
+	return fmt.Sprintf("  %-12s %10.4f   [%8.4f, %8.4f] %10.4f   [%8.4f, %8.4f] %9.1f%%\n",
+		label, raw, rawCI[0], rawCI[1], cv.Estimate, cv.Confidence[0], cv.Confidence[1], cv.VarianceReduction*100)
+}
+
+// formatWelchPlot draws the Welch moving average of the pilot runs as a
+// terminal chart with the chosen truncation point marked.
+func (tv *TerminalVisualizer) formatWelchPlot(warmup *models.WarmupAnalysis) string {
+	const width, height = 70, 12
+
+	plotStr := fmt.Sprintf("\nWARM-UP DETECTION (%s, %d pilot runs, window %d):\n",
+		warmup.Method, warmup.PilotReplications, warmup.Window)
+	plotStr += fmt.Sprintf("  Truncation Point:             %12d customers\n", warmup.TruncationCustomers)
+
+	series := warmup.MovingAverage
+	if len(series) < 2 {
+		return plotStr
+	}
+
+	columns := make([]float64, width)
+	for c := range columns {
+		columns[c] = series[c*(len(series)-1)/(width-1)]
+	}
+	low, high := columns[0], columns[0]
+	for _, v := range columns {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	if high == low {
+		high = low + 1
+	}
+	marker := warmup.TruncationCustomers * (width - 1) / (len(series) - 1)
+
+	for row := height - 1; row >= 0; row-- {
+		level := low + (high-low)*float64(row)/float64(height-1)
+		line := make([]byte, width)
+		for c, v := range columns {
+			switch {
+			case int(math.Round((v-low)/(high-low)*float64(height-1))) == row:
+				line[c] = '*'
+			case c == marker:
+				line[c] = '|'
+			default:
+				line[c] = ' '
+			}
+		}
+		plotStr += fmt.Sprintf("  %8.3f |%s\n", level, string(line))
+	}
+	plotStr += fmt.Sprintf("  %8s +%s\n", "", strings.Repeat("-", width))
+	plotStr += fmt.Sprintf("  %8s  0%*d (customer index)\n", "", width-1, len(series)-1)
+	return plotStr
+}
+
 func (tv *TerminalVisualizer) DisplayExecutionInfo(executionTime time.Duration, eventsProcessed int) {
 	infoStr := fmt.Sprintf("\nEXECUTION INFORMATION:\n")
 	infoStr += fmt.Sprintf("  Real-time execution: %v\n", executionTime)
@@ -198,3 +674,326 @@ func (tv *TerminalVisualizer) DisplayExecutionInfo(executionTime time.Duration,
 		fmt.Print(infoStr)
 	}
 }
+
+func (tv *TerminalVisualizer) DisplayTransient(analysis *models.TransientAnalysis) {
+	resultsStr := fmt.Sprintf("\n%s\nTRANSIENT ANALYSIS (%d replications, %d initial customers)\n%s\n",
+		strings.Repeat("=", 80), analysis.Replications, analysis.InitialCustomers,
+		strings.Repeat("=", 80))
+
+	resultsStr += fmt.Sprintf("  Ensemble means with %.0f%% t-interval half-widths\n", analysis.ConfidenceLevel*100)
+	resultsStr += fmt.Sprintf("  %8s %16s %16s %16s %16s\n", "Time", "Queue", "System", "Util", "Wait")
+	for _, point := range analysis.Points {
+		resultsStr += fmt.Sprintf("  %8.2f", point.Time)
+		for _, estimate := range []*models.EnsembleEstimate{point.QueueLength, point.InSystem, point.Utilization, point.Wait} {
+			halfWidth := (estimate.Confidence[1] - estimate.Confidence[0]) / 2
+			resultsStr += fmt.Sprintf(" %7.3f ±%7.3f", estimate.Mean, halfWidth)
+		}
+		resultsStr += "\n"
+	}
+	resultsStr += "  Wait is the wait of a customer arriving at that time, over the replications that would admit it\n"
+	if analysis.ExportFile != "" {
+		resultsStr += fmt.Sprintf("  Transient analysis written to %s\n", analysis.ExportFile)
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplaySweep(result *models.SweepResult) {
+	resultsStr := fmt.Sprintf("\n%s\nPARAMETER SWEEP (%d points)\n%s\n",
+		strings.Repeat("=", 80), len(result.Points),
+		strings.Repeat("=", 80))
+
+	if result.HasConfidence {
+		resultsStr += fmt.Sprintf("  Means with %.0f%% t-interval half-widths where replications were run\n", result.ConfidenceLevel*100)
+	}
+	cell := 10
+	if result.HasConfidence {
+		cell = 20
+	}
+	widths := make([]int, len(result.Metrics))
+	resultsStr += " "
+	for _, field := range result.Fields {
+		resultsStr += fmt.Sprintf(" %12s", field)
+	}
+	for i, name := range result.Metrics {
+		widths[i] = max(cell, len(name))
+		resultsStr += fmt.Sprintf(" %*s", widths[i], name)
+	}
+	resultsStr += "\n"
+	for _, point := range result.Points {
+		resultsStr += " "
+		for _, setting := range point.Settings {
+			resultsStr += fmt.Sprintf(" %12s", setting.Value)
+		}
+		for i, metric := range point.Metrics {
+			mean, confidence := sweepEstimate(metric)
+			value := fmt.Sprintf("%10.4f", mean)
+			if result.HasConfidence {
+				value += fmt.Sprintf(" ±%8.4f", (confidence[1]-confidence[0])/2)
+			}
+			resultsStr += fmt.Sprintf(" %*s", widths[i], value)
+		}
+		resultsStr += "\n"
+	}
+	if result.OutputFile != "" {
+		resultsStr += fmt.Sprintf("  Sweep table written to %s\n", result.OutputFile)
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplayDesign(design *models.ExperimentDesign) {
+	resultsStr := fmt.Sprintf("\n%s\nEXPERIMENT DESIGN (%s, %d runs)\n%s\n",
+		strings.Repeat("=", 80), design.Type, len(design.Coded),
+		strings.Repeat("=", 80))
+
+	for j, factor := range design.Factors {
+		resultsStr += fmt.Sprintf("  %s = %-28s low %10g  high %10g\n", factorLetter(j), factor.Field, factor.Low, factor.High)
+	}
+	if len(design.Generators) > 0 {
+		resultsStr += fmt.Sprintf("  Generators: %s (resolution %d)\n", strings.Join(design.Generators, ", "), design.Resolution)
+	}
+
+	for _, response := range design.Effects {
+		resultsStr += fmt.Sprintf("\nEFFECTS ON %s (low to high, %.0f%% CI):\n", strings.ToUpper(response.Response), design.Sweep.ConfidenceLevel*100)
+		if response.Unfitted != "" {
+			resultsStr += fmt.Sprintf("  Not estimated: %s\n", response.Unfitted)
+			continue
+		}
+		width := 30
+		for _, effect := range response.Effects {
+			width = max(width, len(effect.Term))
+		}
+		resultsStr += fmt.Sprintf("  %-*s %12s %12s %12s %12s\n", width, "Term", "Effect", "Std Error", "CI Low", "CI High")
+		for _, effect := range response.Effects {
+			resultsStr += fmt.Sprintf("  %-*s %12.4f", width, effect.Term, effect.Effect)
+			if effect.HasConfidence {
+				resultsStr += fmt.Sprintf(" %12.4f %12.4f %12.4f", effect.StdError, effect.Confidence[0], effect.Confidence[1])
+				if effect.Confidence[0] > 0 || effect.Confidence[1] < 0 {
+					resultsStr += " *"
+				}
+			}
+			resultsStr += "\n"
+			if len(effect.Aliases) > 0 {
+				resultsStr += fmt.Sprintf("    aliased with %s\n", strings.Join(effect.Aliases, ", "))
+			}
+		}
+		resultsStr += fmt.Sprintf("  R-squared: %.4f over %d runs, %d degrees of freedom for error\n", response.RSquared, response.Points, response.Freedom)
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplayMetamodel(model *models.Metamodel) {
+	resultsStr := fmt.Sprintf("\n%s\nMETAMODEL OF %s (degree %d polynomial, %d points)\n%s\n",
+		strings.Repeat("=", 80), strings.ToUpper(model.Response), model.Degree, model.Points,
+		strings.Repeat("=", 80))
+
+	for _, factor := range model.Factors {
+		resultsStr += fmt.Sprintf("  %-30s coded from [%g, %g] to [-1, 1]\n", factor.Field, factor.Low, factor.High)
+	}
+
+	width := 30
+	for _, term := range model.Terms {
+		width = max(width, len(term.Label))
+	}
+	resultsStr += fmt.Sprintf("\n  %-*s %14s %12s\n", width, "Term", "Coefficient", "Std Error")
+	for _, term := range model.Terms {
+		resultsStr += fmt.Sprintf("  %-*s %14.6f %12.6f\n", width, term.Label, term.Coefficient, term.StdError)
+	}
+
+	resultsStr += "\nGOODNESS OF FIT:\n"
+	resultsStr += fmt.Sprintf("  R-squared:                    %12.4f\n", model.RSquared)
+	resultsStr += fmt.Sprintf("  Adjusted R-squared:           %12.4f\n", model.AdjustedRSquared)
+	resultsStr += fmt.Sprintf("  Predicted R-squared (LOO):    %12.4f\n", model.PredictedRSquared)
+	resultsStr += fmt.Sprintf("  Residual Std Error:           %12.6f\n", model.ResidualStdError)
+	resultsStr += fmt.Sprintf("  Degrees of Freedom:           %12d\n", model.Freedom)
+	if model.OutputFile != "" {
+		resultsStr += fmt.Sprintf("  Saved to %s\n", model.OutputFile)
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplayPrediction(prediction *models.MetamodelPrediction) {
+	resultsStr := fmt.Sprintf("\n%s\nPREDICTED %s\n%s\n",
+		strings.Repeat("=", 80), strings.ToUpper(prediction.Response),
+		strings.Repeat("=", 80))
+
+	for _, setting := range prediction.Settings {
+		resultsStr += fmt.Sprintf("  %-30s %s\n", setting.Field, setting.Value)
+	}
+	resultsStr += fmt.Sprintf("\n  Prediction:                   %12.6f\n", prediction.Mean)
+	if prediction.HasConfidence {
+		resultsStr += fmt.Sprintf("  Std Error:                    %12.6f\n", prediction.StdError)
+		resultsStr += fmt.Sprintf("  CI:                           [%10.6f, %10.6f]\n", prediction.Confidence[0], prediction.Confidence[1])
+	}
+	if len(prediction.Extrapolated) > 0 {
+		resultsStr += fmt.Sprintf("  WARNING: extrapolating beyond the fitted range of %s\n", strings.Join(prediction.Extrapolated, ", "))
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplayOptimization(result *models.OptimizationResult) {
+	resultsStr := fmt.Sprintf("\n%s\nOPTIMIZATION OF %s (%d candidates evaluated)\n%s\n",
+		strings.Repeat("=", 80), strings.ToUpper(result.Field), len(result.Candidates),
+		strings.Repeat("=", 80))
+
+	resultsStr += fmt.Sprintf("  Constraints judged on %.0f%% t-intervals\n", result.ConfidenceLevel*100)
+	for _, candidate := range result.Candidates {
+		verdict := "infeasible"
+		switch {
+		case candidate.Feasible:
+			verdict = "feasible"
+		case !candidate.Conclusive:
+			verdict = "undecided"
+		}
+		resultsStr += fmt.Sprintf("\n  %s = %s: %s after %d replications\n", result.Field, candidate.Value, verdict, candidate.Replications)
+		for _, check := range candidate.Constraints {
+			relation := "<="
+			if check.Type == constraintMin {
+				relation = ">="
+			}
+			mark := "no"
+			switch {
+			case check.Met:
+				mark = "yes"
+			case !check.Conclusive:
+				mark = "?"
+			}
+			resultsStr += fmt.Sprintf("    %-28s %s %-10g %10.4f [%10.4f, %10.4f] %4s\n", check.Metric, relation, check.Limit,
+				check.Estimate, check.Confidence[0], check.Confidence[1], mark)
+		}
+		if candidate.Cost != nil {
+			resultsStr += fmt.Sprintf("    %-28s %13s %10.4f [%10.4f, %10.4f]\n", result.CostMetric, "", candidate.Cost.Mean,
+				candidate.Cost.Confidence[0], candidate.Cost.Confidence[1])
+		}
+	}
+
+	if result.Best != nil {
+		resultsStr += fmt.Sprintf("\n  CHEAPEST FEASIBLE: %s = %s\n", result.Field, result.Best.Value)
+	} else {
+		resultsStr += "\n  NO FEASIBLE VALUE: the largest candidate misses the constraints\n"
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplaySelection(result *models.SelectionResult) {
+	resultsStr := fmt.Sprintf("\n%s\nRANKING AND SELECTION (%s, goal %s)\n%s\n",
+		strings.Repeat("=", 80), result.Metric, result.Goal,
+		strings.Repeat("=", 80))
+
+	resultsStr += fmt.Sprintf("  Procedure:                    %12s\n", "Kim-Nelson")
+	resultsStr += fmt.Sprintf("  Indifference Zone:            %12.4f\n", result.Indifference)
+	resultsStr += fmt.Sprintf("  Probability of Correct Sel.:  %12.4f\n", result.Probability)
+	resultsStr += fmt.Sprintf("  Initial Replications:         %12d\n", result.InitialReplications)
+	resultsStr += fmt.Sprintf("  Common Random Numbers:        %12v\n", result.CommonRandomNumbers)
+
+	width := len("System")
+	for _, system := range result.Systems {
+		width = max(width, len(system.Name))
+	}
+	resultsStr += fmt.Sprintf("\n  %-*s %12s %14s %14s  %s\n", width, "System", "Replications", "Mean", "Std Dev", "Status")
+	for _, system := range result.Systems {
+		status := "survived"
+		switch {
+		case system == result.Best:
+			status = "selected"
+		case system.Eliminated:
+			status = "eliminated by " + system.EliminatedBy
+		}
+		resultsStr += fmt.Sprintf("  %-*s %12d %14.4f %14.4f  %s\n", width, system.Name, system.Replications,
+			system.Mean, system.StdDev, status)
+	}
+
+	if result.Truncated {
+		resultsStr += fmt.Sprintf("\n  BEST MEAN AT THE REPLICATION LIMIT: %s (the guarantee does not hold)\n", result.Best.Name)
+	} else {
+		resultsStr += fmt.Sprintf("\n  SELECTED: %s, best within %g with probability >= %.2f\n", result.Best.Name,
+			result.Indifference, result.Probability)
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
+
+func (tv *TerminalVisualizer) DisplayComparison(comparison *models.ScenarioComparison) {
+	resultsStr := fmt.Sprintf("\n%s\nSCENARIO COMPARISON (%s)\n%s\n",
+		strings.Repeat("=", 80), comparison.Metric,
+		strings.Repeat("=", 80))
+
+	if comparison.NameA != "" {
+		resultsStr += fmt.Sprintf("  Scenario A:                   %s\n", comparison.NameA)
+		resultsStr += fmt.Sprintf("  Scenario B:                   %s\n", comparison.NameB)
+	}
+	resultsStr += fmt.Sprintf("  Replication Pairs:            %12d\n", comparison.Pairs)
+	resultsStr += fmt.Sprintf("  Common Random Numbers:        %12v\n", comparison.CommonRandomNumbers)
+	resultsStr += fmt.Sprintf("  Antithetic Variates:          %12v\n", comparison.Antithetic)
+
+	resultsStr += fmt.Sprintf("\n  %-6s %14s %14s %14s\n", "Pair", "Scenario A", "Scenario B", "A - B")
+	for i := range comparison.ValuesA {
+		resultsStr += fmt.Sprintf("  %-6d %14.4f %14.4f %14.4f\n", i+1,
+			comparison.ValuesA[i], comparison.ValuesB[i], comparison.ValuesA[i]-comparison.ValuesB[i])
+	}
+
+	resultsStr += "\nDIFFERENCE ESTIMATE:\n"
+	resultsStr += fmt.Sprintf("  Mean Scenario A:              %12.4f\n", comparison.MeanA)
+	resultsStr += fmt.Sprintf("  Mean Scenario B:              %12.4f\n", comparison.MeanB)
+	resultsStr += fmt.Sprintf("  Mean Difference (A - B):      %12.4f\n", comparison.MeanDifference)
+	resultsStr += fmt.Sprintf("  Interval Method:              %12s\n", comparison.Method)
+	resultsStr += fmt.Sprintf("  Degrees of Freedom:           %12.1f\n", comparison.Freedom)
+	resultsStr += fmt.Sprintf("  %.0f%% CI:                       [%8.4f, %8.4f]\n", comparison.ConfidenceLevel*100,
+		comparison.DifferenceConfidence[0], comparison.DifferenceConfidence[1])
+
+	resultsStr += "\nVARIANCE REDUCTION:\n"
+	resultsStr += fmt.Sprintf("  Var(A - B) observed:          %12.6f\n", comparison.DifferenceVariance)
+	resultsStr += fmt.Sprintf("  Var(A) + Var(B):              %12.6f\n", comparison.IndependentVariance)
+	resultsStr += fmt.Sprintf("  Reduction on Differences:     %12.2f %%\n", comparison.VarianceReduction*100)
+	if comparison.Antithetic {
+		resultsStr += fmt.Sprintf("  Antithetic Reduction (A):     %12.2f %%\n", comparison.AntitheticReductionA*100)
+		resultsStr += fmt.Sprintf("  Antithetic Reduction (B):     %12.2f %%\n", comparison.AntitheticReductionB*100)
+	}
+	resultsStr += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+
+	if tv.logger != nil {
+		tv.logger.LogTerminal(resultsStr)
+	} else {
+		fmt.Print(resultsStr)
+	}
+}
@@ -0,0 +1,147 @@
+package simulation
+
+import (
+	"des/models"
+	"math"
+)
+
+const (
+	defaultPilotReplications = 5
+	defaultWelchWindow       = 10
+	welchTolerance           = 0.05
+	mserBatchSize            = 5
+)
+
+// DetectWarmup runs pilot replications of config without any warm-up and
+// picks the number of completed customers to delete, using either Welch's
+// moving-average method or MSER-5 on the across-replication average of the
+// per-customer wait times.
+func DetectWarmup(config *models.SimulationConfig) *models.WarmupAnalysis {
+	pilots := config.Warmup.PilotReplications
+	if pilots <= 0 {
+		pilots = defaultPilotReplications
+	}
+	window := config.Warmup.WelchWindow
+	if window <= 0 {
+		window = defaultWelchWindow
+	}
+
+	pilotConfig := *config
+	pilotConfig.Warmup = models.WarmupConfig{}
+	pilotConfig.Streaming.Enabled = false
+	base := ResolveSeed(config.Random.Seed)
+
+	series := make([][]float64, pilots)
+	runParallel(Workers(config), pilots, nil, func(i int) {
+		sim := newSilentSimulator(&pilotConfig, DeriveSeed(base, uint64(i)), false)
+		sim.Run()
+		series[i] = sim.stats.waitTimes
+	})
+	length := math.MaxInt
+	for _, waits := range series {
+		if len(waits) < length {
+			length = len(waits)
+		}
+	}
+
+	averaged := make([]float64, length)
+	for i := range averaged {
+		for _, s := range series {
+			averaged[i] += s[i]
+		}
+		averaged[i] /= float64(pilots)
+	}
+
+	analysis := &models.WarmupAnalysis{
+		Method:            config.Warmup.Method,
+		PilotReplications: pilots,
+		Window:            window,
+		AveragedSeries:    averaged,
+		MovingAverage:     welchMovingAverage(averaged, window),
+	}
+	if config.Warmup.Method == "mser5" {
+		analysis.TruncationCustomers = mserTruncation(averaged, mserBatchSize)
+	} else {
+		analysis.TruncationCustomers = welchTruncation(analysis.MovingAverage)
+	}
+	return analysis
+}
+
+// ResolveWarmup returns config with any automatic warm-up detection replaced
+// by the detected customer count, so that repeated runs share one pilot study.
+func ResolveWarmup(config *models.SimulationConfig) *models.SimulationConfig {
+	if config.Warmup.Method == "" {
+		return config
+	}
+	analysis := DetectWarmup(config)
+	resolved := *config
+	resolved.Warmup.Method = ""
+	resolved.Warmup.Time = 0
+	resolved.Warmup.Customers = analysis.TruncationCustomers
+	return &resolved
+}
+
+// welchMovingAverage smooths the series with a centred window of 2w+1
+// points, shrinking the window near the start as in Welch's procedure. As
+// Welch recommends, only the first half of the series is smoothed.
+func welchMovingAverage(series []float64, w int) []float64 {
+	m := len(series) / 2
+	smoothed := make([]float64, m)
+	for i := 0; i < m; i++ {
+		half := w
+		if i < w {
+			half = i
+		}
+		sum := 0.0
+		for j := i - half; j <= i+half; j++ {
+			sum += series[j]
+		}
+		smoothed[i] = sum / float64(2*half+1)
+	}
+	return smoothed
+}
+
+// welchTruncation returns the first index at which the moving average enters
+// a band around the level of its second half.
+func welchTruncation(smoothed []float64) int {
+	if len(smoothed) < 2 {
+		return 0
+	}
+	level := sampleMean(smoothed[len(smoothed)/2:])
+	tolerance := welchTolerance * math.Abs(level)
+	for i, v := range smoothed {
+		if math.Abs(v-level) <= tolerance {
+			return i
+		}
+	}
+	return len(smoothed) / 2
+}
+
+// mserTruncation applies the MSER-k rule: batch the series into means of k,
+// then choose the deletion d (in batches, at most half of them) minimising
+// the marginal standard error sum((z_i - mean_d)^2) / (n-d)^2.
+func mserTruncation(series []float64, k int) int {
+	n := len(series) / k
+	if n < 2 {
+		return 0
+	}
+	batches := make([]float64, n)
+	for i := 0; i < n; i++ {
+		batches[i] = sampleMean(series[i*k : (i+1)*k])
+	}
+
+	best, bestValue := 0, math.Inf(1)
+	for d := 0; d <= n/2; d++ {
+		remaining := batches[d:]
+		mean := sampleMean(remaining)
+		sumSq := 0.0
+		for _, z := range remaining {
+			sumSq += (z - mean) * (z - mean)
+		}
+		value := sumSq / float64(len(remaining)*len(remaining))
+		if value < bestValue {
+			best, bestValue = d, value
+		}
+	}
+	return best * k
+}
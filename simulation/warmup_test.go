@@ -0,0 +1,59 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWelchMovingAverage(t *testing.T) {
+	squares := make([]float64, 12)
+	for i := range squares {
+		squares[i] = float64(i * i)
+	}
+	// the first half is smoothed; windows shrink to 1, 3, ... at the start
+	want := []float64{0, (0 + 1 + 4) / 3.0, (0 + 1 + 4 + 9 + 16) / 5.0, (1 + 4 + 9 + 16 + 25) / 5.0,
+		(4 + 9 + 16 + 25 + 36) / 5.0, (9 + 16 + 25 + 36 + 49) / 5.0}
+	got := welchMovingAverage(squares, 2)
+	if len(got) != len(want) {
+		t.Fatalf("%d smoothed values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("smoothed[%d] = %g, want %g", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWarmupTruncation(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  func([]float64) int
+		input []float64
+		want  int
+	}{
+		// the second half averages 2; 2.05 is the first value within 5%
+		{"welch", welchTruncation, []float64{10, 6, 3, 2.05, 2, 2, 2, 2}, 3},
+		{"welch never settles", welchTruncation, []float64{10, 8, 6, 4, 1, 3, 1, 3}, 4},
+		// deleting 2 leaves 1, 3, ... with mean 2 and MSER 8/8^2 = 0.125,
+		// against 16/81 for 1, 6.857/49 for 3 and 6/36 for 4 deleted
+		{"mser, batches of 1", func(s []float64) int { return mserTruncation(s, 1) }, []float64{9, 5, 1, 3, 1, 3, 1, 3, 1, 3}, 2},
+		// the same batch means from batches of 5 truncate 2 batches
+		{"mser, batches of 5", func(s []float64) int { return mserTruncation(s, 5) }, repeatEach([]float64{9, 5, 1, 3, 1, 3, 1, 3, 1, 3}, 5), 10},
+		{"mser, no transient", func(s []float64) int { return mserTruncation(s, 1) }, []float64{2, 2, 2, 2, 2, 2}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.rule(tt.input); got != tt.want {
+			t.Errorf("%s: truncation %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func repeatEach(values []float64, k int) []float64 {
+	var repeated []float64
+	for _, v := range values {
+		for i := 0; i < k; i++ {
+			repeated = append(repeated, v)
+		}
+	}
+	return repeated
+}